@@ -0,0 +1,419 @@
+// Package article extracts the main content of web pages like the reader
+// modes of browsers. Paragraphs are scored by their length and commas, their
+// scores add up in their ancestors and the best scoring ancestor is taken as
+// the article together with its siblings that score close to it.
+package article
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// minParagraphLength is the length of text a paragraph needs to be scored
+	minParagraphLength = 25
+)
+
+var (
+	// ErrNoContent is returned when no content could be found in a page
+	ErrNoContent = errors.New("no article content found")
+
+	// unlikely matches the classes and IDs of elements that are removed
+	// unless they also match maybe
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|disqus|extra|foot|header|legends|menu|modal|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|ad-break|agegate|pagination|pager|popup|promo|subscribe|newsletter`)
+	maybe    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+
+	// positive and negative match the classes and IDs that make elements
+	// more or less likely to be the article
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negative = regexp.MustCompile(`(?i)-ad-|hidden|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	// removed are the elements that are never part of an article
+	removed = map[string]bool{
+		"script": true, "style": true, "noscript": true, "iframe": true, "object": true,
+		"embed": true, "form": true, "input": true, "button": true, "select": true,
+		"textarea": true, "nav": true, "aside": true, "footer": true, "svg": true,
+		"link": true, "meta": true,
+	}
+
+	// blocks are the elements that keep a div from being scored as a
+	// paragraph
+	blocks = map[string]bool{
+		"blockquote": true, "dl": true, "div": true, "img": true, "ol": true, "p": true,
+		"pre": true, "table": true, "ul": true, "section": true, "article": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	}
+
+	// kept are the attributes kept in the article
+	kept = map[string]bool{"href": true, "src": true, "alt": true, "title": true}
+)
+
+// Extract returns the HTML of the main content of a page, relative links are
+// resolved against the base URL of the page
+func Extract(r io.Reader, base *url.URL) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	body := find(doc, "body")
+	if body == nil {
+		return "", ErrNoContent
+	}
+
+	prepare(body)
+
+	best, scores := topCandidate(body)
+	if best == nil {
+		return "", ErrNoContent
+	}
+
+	article := &html.Node{Type: html.ElementNode, Data: "div"}
+	for _, n := range related(best, scores) {
+		n.Parent.RemoveChild(n)
+		article.AppendChild(n)
+	}
+
+	clean(article, base)
+
+	var b bytes.Buffer
+	for c := article.FirstChild; c != nil; c = c.NextSibling {
+		err = html.Render(&b, c)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// prepare removes the elements that aren't part of an article
+func prepare(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		switch {
+		case c.Type == html.CommentNode:
+			n.RemoveChild(c)
+		case c.Type != html.ElementNode:
+		case removed[c.Data] || unlikelyCandidate(c):
+			n.RemoveChild(c)
+		default:
+			prepare(c)
+		}
+
+		c = next
+	}
+}
+
+// unlikelyCandidate returns whether an element is unlikely to be part of an
+// article by its class and ID
+func unlikelyCandidate(n *html.Node) bool {
+	switch n.Data {
+	case "body", "article", "main", "a":
+		return false
+	}
+
+	names := attr(n, "class") + " " + attr(n, "id")
+
+	return unlikely.MatchString(names) && !maybe.MatchString(names)
+}
+
+// topCandidate scores the paragraphs of the body and returns the element
+// with the best score adjusted by its density of links, together with the
+// scores of all candidates
+func topCandidate(body *html.Node) (*html.Node, map[*html.Node]float64) {
+	var candidates []*html.Node
+	scores := make(map[*html.Node]float64)
+
+	var score func(n *html.Node)
+	score = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				score(c)
+			}
+		}
+
+		if !paragraph(n) {
+			return
+		}
+
+		text := innerText(n)
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLength {
+			return
+		}
+
+		points := 1 + float64(strings.Count(text, ",")) + math.Min(float64(length/100), 3)
+
+		// The parent gets the points and the grandparent half of them
+		for _, ancestor := range []*html.Node{n.Parent, n.Parent.Parent} {
+			if ancestor == nil || ancestor.Type != html.ElementNode {
+				break
+			}
+
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+
+			scores[ancestor] += points
+			points /= 2
+		}
+	}
+
+	score(body)
+
+	// Candidates are in the order they were found so ties are broken the
+	// same way every time
+	var best *html.Node
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+
+	return best, scores
+}
+
+// related returns the top candidate and its siblings that are part of the
+// article, such as paragraphs next to the element holding most of them
+func related(best *html.Node, scores map[*html.Node]float64) []*html.Node {
+	if best.Parent == nil {
+		return []*html.Node{best}
+	}
+
+	threshold := math.Max(10, scores[best]*0.2)
+
+	var nodes []*html.Node
+	for s := best.Parent.FirstChild; s != nil; s = s.NextSibling {
+		if s.Type != html.ElementNode {
+			continue
+		}
+
+		include := s == best
+		if score, ok := scores[s]; ok && score >= threshold {
+			include = true
+		}
+
+		if s.Data == "p" && !include {
+			text := innerText(s)
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(s)
+
+			include = length > 80 && density < 0.25 ||
+				length > 0 && length <= 80 && density == 0 && strings.HasSuffix(text, ".")
+		}
+
+		if include {
+			nodes = append(nodes, s)
+		}
+	}
+
+	return nodes
+}
+
+// clean removes the elements of the article that are mostly links, such as
+// lists of related articles, and the attributes that aren't needed to read
+// it. Links and images are resolved against the base URL.
+func clean(n *html.Node, base *url.URL) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "div", "section", "ul", "ol", "table":
+				if linkDensity(c) > 0.5 || classWeight(c) < 0 && utf8.RuneCountInString(innerText(c)) < 200 {
+					n.RemoveChild(c)
+					c = next
+					continue
+				}
+			}
+
+			cleanAttrs(c, base)
+			clean(c, base)
+		}
+
+		c = next
+	}
+}
+
+// cleanAttrs keeps the attributes of an element needed to read it, lazy
+// loaded images get their source from data-src
+func cleanAttrs(n *html.Node, base *url.URL) {
+	var attrs []html.Attribute
+	var src string
+
+	for _, a := range n.Attr {
+		if !kept[a.Key] || a.Namespace != "" || a.Key == "src" && a.Val == "" {
+			continue
+		}
+
+		switch a.Key {
+		case "src":
+			src = a.Val
+			a.Val = resolve(base, a.Val)
+		case "href":
+			a.Val = resolve(base, a.Val)
+		}
+
+		attrs = append(attrs, a)
+	}
+
+	if lazy := attr(n, "data-src"); n.Data == "img" && src == "" && lazy != "" {
+		attrs = append(attrs, html.Attribute{Key: "src", Val: resolve(base, lazy)})
+	}
+
+	n.Attr = attrs
+}
+
+// resolve returns the URL resolved against the base URL, URLs that can't be
+// parsed are returned as is
+func resolve(base *url.URL, s string) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || base == nil {
+		return s
+	}
+
+	return base.ResolveReference(u).String()
+}
+
+// paragraph returns whether an element is scored as a paragraph, divs
+// without blocks are paragraphs too
+func paragraph(n *html.Node) bool {
+	switch n.Data {
+	case "p", "pre", "td", "blockquote":
+		return true
+	case "div":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blocks[c.Data] {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// initialScore returns the score of a candidate before its paragraphs are
+// added, by its tag and its class and ID
+func initialScore(n *html.Node) float64 {
+	var score float64
+
+	switch n.Data {
+	case "div", "article", "main":
+		score = 5
+	case "pre", "td", "blockquote":
+		score = 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score = -5
+	}
+
+	return score + classWeight(n)
+}
+
+// classWeight returns the weight of an element by its class and ID
+func classWeight(n *html.Node) float64 {
+	var weight float64
+
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+
+		if negative.MatchString(name) {
+			weight -= 25
+		}
+		if positive.MatchString(name) {
+			weight += 25
+		}
+	}
+
+	return weight
+}
+
+// linkDensity returns the share of the text of an element that is in links
+func linkDensity(n *html.Node) float64 {
+	length := utf8.RuneCountInString(innerText(n))
+	if length == 0 {
+		return 0
+	}
+
+	var links int
+	var count func(n *html.Node)
+	count = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "a" {
+				links += utf8.RuneCountInString(innerText(c))
+				continue
+			}
+
+			count(c)
+		}
+	}
+
+	count(n)
+
+	return float64(links) / float64(length)
+}
+
+// innerText returns the text of an element with whitespace collapsed
+func innerText(n *html.Node) string {
+	var b strings.Builder
+
+	var write func(n *html.Node)
+	write = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			write(c)
+		}
+	}
+
+	write(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// find returns the first element with the tag
+func find(n *html.Node, tag string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == tag {
+		return n
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := find(c, tag); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// attr returns the value of an attribute of an element
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
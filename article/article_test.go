@@ -0,0 +1,74 @@
+package article_test
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"feeda/article"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>A post</title><script>var tracking = true;</script></head>
+<body>
+<header class="site-header"><a href="/">Home</a> <a href="/about">About</a></header>
+<nav><ul><li><a href="/a">Archive</a></li><li><a href="/b">Tags</a></li></ul></nav>
+<div id="main">
+	<div class="post-content">
+		<h1>The title of the post</h1>
+		<p>The first paragraph of the article is long enough to be scored, with commas, and more words to read.</p>
+		<p>The second paragraph links to <a href="/other">another post</a> and shows an image, which is lazy loaded.</p>
+		<img data-src="images/photo.jpg" alt="A photo" class="lazy">
+		<p>The third paragraph, like the others, is part of the article that readers came for.</p>
+		<div class="share-buttons"><a href="https://social.example/share">Share</a></div>
+	</div>
+	<div class="comments"><p>A comment that is long enough to be scored, but isn't part of the article.</p></div>
+</div>
+<div class="sidebar"><p>Sidebar text that is long enough to be scored, with commas, but is not the article.</p></div>
+<footer><p>Copyright, all rights reserved, by the author of the blog.</p></footer>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	base, err := url.Parse("https://blog.example/posts/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := article.Extract(strings.NewReader(testPage), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"The first paragraph",
+		"The second paragraph",
+		"The third paragraph",
+		`<a href="https://blog.example/other">another post</a>`,
+		`<img alt="A photo" src="https://blog.example/posts/images/photo.jpg"/>`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("expecting content to contain %q, got %q", expected, content)
+		}
+	}
+
+	for _, unexpected := range []string{"tracking", "Archive", "Share", "comment", "Sidebar", "Copyright", "class="} {
+		if strings.Contains(content, unexpected) {
+			t.Fatalf("expecting content not to contain %q, got %q", unexpected, content)
+		}
+	}
+}
+
+func TestExtractNoContent(t *testing.T) {
+	for i, page := range []string{
+		"",
+		"<html><body><nav><a href=\"/\">Home</a></nav></body></html>",
+		"<p>Too short</p>",
+	} {
+		_, err := article.Extract(strings.NewReader(page), nil)
+		if err != article.ErrNoContent {
+			t.Fatalf("%d: expecting ErrNoContent, got %v", i, err)
+		}
+	}
+}
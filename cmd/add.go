@@ -2,32 +2,53 @@ package cmd
 
 import (
 	"bytes"
-	"io/ioutil"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"net/url"
+	"strings"
 	"sync"
-	"time"
 
 	"feeda/sqlite"
 
 	"github.com/spf13/cobra"
 )
 
+var pick *[]int
+
 // addCmd adds one or multiple URLs of RSS feeds to the DB
 var addCmd = &cobra.Command{
 	Use:   "add [URL of feed] [URL of feed 2]...",
 	Short: "Add RSS feeds",
-	Long:  `Adds multiple RSS feeds to aggregate.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Adds multiple RSS, RDF, Atom or JSON feeds to aggregate.
+
+The URL can also be a web page, the feeds advertised by the page will then be
+added. If the page doesn't advertise any feeds then common paths such as /feed
+and /rss.xml are tried. When a page offers several feeds they are listed and
+the ones to add must be picked with --pick, which only takes a single URL as
+the numbers refer to the feeds of that page. Example:
+
+# List the feeds offered by a blog
+feeda add https://blog.example.com
+
+# Add the first and third feed offered by the blog
+feeda add --pick=1,3 https://blog.example.com`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		var err error
 
-		c := &http.Client{
-			Timeout: 10 * time.Second,
+		if len(*pick) > 0 && len(args) > 1 {
+			log.Fatal("--pick can only be used with a single URL")
 		}
+
+		c := newHTTPClient()
 		var wg sync.WaitGroup
+		var mu sync.Mutex
 		var feeds []sqlite.Feed
+		var unpicked []string
 
 		for _, arg := range args {
 			_, err = url.Parse(arg)
@@ -37,50 +58,135 @@ var addCmd = &cobra.Command{
 
 			wg.Add(1)
 			go func(url string) {
-				req, err := http.NewRequest(http.MethodGet, url, nil)
-				if err != nil {
-					log.Fatal(err)
-				}
+				defer wg.Done()
 
-				req.Header.Set("User-Agent", userAgent)
-				resp, err := c.Do(req)
+				found, err := discoverFeeds(c, url)
 				if err != nil {
-					log.Fatalf("could not fetch URL %s: %v", url, err)
+					log.Fatalf("could not add URL %s: %v", url, err)
 				}
-				defer resp.Body.Close()
 
-				body, err := ioutil.ReadAll(resp.Body)
-				if err != nil {
-					log.Fatal(err)
-				}
+				found, err = pickFeeds(found, *pick)
 
-				feed := sqlite.Feed{
-					URL:  url,
-					Type: sqlite.FeedTypeRSS,
-				}
+				mu.Lock()
+				defer mu.Unlock()
 
-				if isAtom(body) {
-					feed.Type = sqlite.FeedTypeAtom
+				if err != nil {
+					fmt.Printf("%s offers several feeds:\n", url)
+					for i, feed := range found {
+						fmt.Printf("%d. %s (%s)\n", i+1, feed.URL, feed.Type)
+					}
+					log.Printf("%s: %v", url, err)
+					unpicked = append(unpicked, url)
+
+					return
 				}
 
-				feeds = append(feeds, feed)
-				wg.Done()
+				feeds = append(feeds, found...)
 			}(arg)
 		}
 
 		wg.Wait()
 
-		err = sqlite.CreateIgnoreFeeds(db, feeds...)
-		if err != nil {
-			log.Fatal("could not add feeds:", err)
+		if len(feeds) > 0 {
+			err = sqlite.CreateIgnoreFeeds(db, feeds...)
+			if err != nil {
+				log.Fatal("could not add feeds:", err)
+			}
+		}
+
+		if len(unpicked) > 0 {
+			log.Fatalf("no feeds added for %s", strings.Join(unpicked, ", "))
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(addCmd)
+
+	pick = addCmd.Flags().IntSliceP("pick", "p", nil, "Numbers of the feeds to add when a page offers several")
 }
 
-func isAtom(b []byte) bool {
-	return bytes.Contains(b, []byte("<feed"))
+// pickFeeds returns the picked feeds when there are several to choose from,
+// the picks are 1-based. If there are several feeds and none are picked then
+// all feeds are returned together with an error.
+func pickFeeds(feeds []sqlite.Feed, picks []int) ([]sqlite.Feed, error) {
+	if len(feeds) < 2 {
+		return feeds, nil
+	}
+
+	if len(picks) == 0 {
+		return feeds, errors.New("choose the feeds to add with --pick")
+	}
+
+	var picked []sqlite.Feed
+	for _, p := range picks {
+		if p < 1 || p > len(feeds) {
+			return feeds, fmt.Errorf("picked feed %d doesn't exist", p)
+		}
+
+		picked = append(picked, feeds[p-1])
+	}
+
+	return picked, nil
+}
+
+// newFeed creates a feed for the URL with the type detected from the fetched
+// body and the metadata parsed from it
+func newFeed(url string, body []byte) (sqlite.Feed, error) {
+	feed := sqlite.Feed{URL: url}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var content jsonFeed
+		err := json.Unmarshal(trimmed, &content)
+		if err != nil {
+			return feed, err
+		}
+
+		if !strings.HasPrefix(content.Version, "https://jsonfeed.org/version/") {
+			return feed, fmt.Errorf("unsupported JSON document with version %q", content.Version)
+		}
+
+		feed.Type = sqlite.FeedTypeJSON
+	} else {
+		root, err := xmlRoot(trimmed)
+		if err != nil {
+			return feed, err
+		}
+
+		switch root.Local {
+		case "rss":
+			feed.Type = sqlite.FeedTypeRSS
+		case "RDF":
+			feed.Type = sqlite.FeedTypeRDF
+		case "feed":
+			feed.Type = sqlite.FeedTypeAtom
+		default:
+			return feed, fmt.Errorf("unsupported XML document with root element <%s>", root.Local)
+		}
+	}
+
+	feed, _, err := parseFeed(bytes.NewReader(body), feed)
+
+	return feed, err
+}
+
+// xmlRoot returns the name of the root element of a XML document
+func xmlRoot(b []byte) (xml.Name, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	// Only the root element is needed so don't care about the charset
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("could not find root element: %v", err)
+		}
+
+		if se, ok := t.(xml.StartElement); ok {
+			return se.Name, nil
+		}
+	}
 }
@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"feeda/article"
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// maxArticleSize is the most bytes of a page read to extract its article
+	maxArticleSize = 10 << 20
+
+	// articleBatchSize is the number of items looked up per statement when
+	// syncing articles
+	articleBatchSize = 100
+
+	// maxConcurrentArticles limits the number of pages fetched at the same
+	// time per command or synced feed
+	maxConcurrentArticles = 4
+)
+
+// fetchArticleCmd extracts the articles of items from their pages
+var fetchArticleCmd = &cobra.Command{
+	Use:   "fetch-article [item IDs]",
+	Short: "Fetch the full articles of items",
+	Long: `Downloads the pages of items and extracts their main content, for feeds that
+only have summaries of their items. The article is kept apart from the
+description of the item and is shown instead of it by list, show and tui.
+Articles of new items are fetched when syncing feeds with full_content set in
+the config file, articles that failed to fetch then are only fetched again by
+this command. Example:
+
+# Fetch the articles of items with ID = 4 and ID = 7
+feeda fetch-article 4 7`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := sqlite.ListItems(db, sqlite.ItemFilter{IDs: parseItemIDs(args)})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(items) == 0 {
+			log.Fatal("no items found")
+		}
+
+		failed := fetchArticles(newHTTPClient(), items, func(item *sqlite.Item, err error) {
+			if err != nil {
+				log.Printf("%d. could not fetch article of %s: %s", item.ID, item.URL, err)
+				return
+			}
+
+			fmt.Printf("%d. article fetched\n", item.ID)
+		})
+
+		if failed > 0 {
+			log.Fatalf("%d of %d articles failed to fetch", failed, len(items))
+		}
+	},
+}
+
+// syncArticles fetches the articles of the new items of a feed with the
+// full_content setting. Failures are logged and not retried by later syncs,
+// the articles can be fetched again with fetch-article.
+func syncArticles(c *http.Client, feed sqlite.Feed, newItems []sqlite.Item) error {
+	if !cfg.feed(feed.URL).FullContent {
+		return nil
+	}
+
+	return sqlite.Batches(len(newItems), articleBatchSize, func(start, end int) error {
+		var guids []string
+		for _, item := range newItems[start:end] {
+			guids = append(guids, item.GUID)
+		}
+
+		missing, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feed.ID, GUIDs: guids, NoContent: true})
+		if err != nil {
+			return err
+		}
+
+		fetchArticles(c, missing, func(item *sqlite.Item, err error) {
+			if err != nil {
+				log.Printf("%d. could not fetch article of item %d: %s", feed.ID, item.ID, err)
+			}
+		})
+
+		return nil
+	})
+}
+
+// fetchArticles fetches and stores the articles of items, a few at a time.
+// done is called with the outcome of each item, one call at a time. Returns
+// the number of failures.
+func fetchArticles(c *http.Client, items []*sqlite.Item, done func(item *sqlite.Item, err error)) int {
+	var failed int
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, maxConcurrentArticles)
+
+	for _, item := range items {
+		wg.Add(1)
+
+		go func(item *sqlite.Item) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			content, err := fetchArticle(c, item.URL)
+			if err == nil {
+				err = sqlite.SetItemContent(db, item.ID, content)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed++
+			}
+
+			done(item, err)
+		}(item)
+	}
+
+	wg.Wait()
+
+	return failed
+}
+
+// fetchArticle downloads a page and returns its article
+func fetchArticle(c *http.Client, u string) (string, error) {
+	if strings.TrimSpace(u) == "" {
+		return "", errors.New("missing URL")
+	}
+
+	req, err := newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && !strings.Contains(mediaType, "html") {
+		return "", fmt.Errorf("unsupported content type %s", mediaType)
+	}
+
+	// Pages are decoded by the charset of their header or meta tag
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxArticleSize), contentType)
+	if err != nil {
+		return "", err
+	}
+
+	// Links are resolved against the URL the page was redirected to
+	return article.Extract(body, resp.Request.URL)
+}
+
+func init() {
+	RootCmd.AddCommand(fetchArticleCmd)
+}
@@ -0,0 +1,368 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/BurntSushi/toml"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+const (
+	configFile = "config.toml"
+)
+
+// Sources of settings
+const (
+	sourceDefault = "default"
+	sourceFile    = "config file"
+)
+
+type (
+	// config is read from ~/.feeda/config.toml, settings of the file are
+	// overridden by environment variables and flags
+	config struct {
+		DB          string   `toml:"db"`
+		Timeout     duration `toml:"timeout"`
+		UserAgent   string   `toml:"user_agent"`
+		Limit       int64    `toml:"limit"`
+		DownloadDir string   `toml:"download_dir"`
+		// Interval is how often feeds are synced by the daemon and sync --due
+		// until it is known how often they are updated
+		Interval duration `toml:"interval"`
+		// MinInterval and MaxInterval bound the intervals of feeds that
+		// aren't set in the config file
+		MinInterval duration `toml:"min_interval"`
+		MaxInterval duration `toml:"max_interval"`
+		// Retention is the policy of prune when no flags are given and of
+		// sync --prune
+		Retention retentionConfig `toml:"retention"`
+		// Templates are the named templates of the list command
+		Templates map[string]string `toml:"templates"`
+		// Feeds override settings for feeds by their URLs
+		Feeds []feedConfig `toml:"feeds"`
+	}
+
+	retentionConfig struct {
+		OlderThan   duration `toml:"older_than"`
+		KeepPerFeed int64    `toml:"keep_per_feed"`
+		ReadOnly    bool     `toml:"read_only"`
+	}
+
+	feedConfig struct {
+		URL      string            `toml:"url"`
+		Timeout  duration          `toml:"timeout"`
+		Interval duration          `toml:"interval"`
+		Headers  map[string]string `toml:"headers"`
+		Username string            `toml:"username"`
+		Password string            `toml:"password"`
+		// FullContent fetches the articles of new items from their pages
+		FullContent bool `toml:"full_content"`
+	}
+
+	// duration is parsed by parseAge so it can be given in days and weeks
+	duration struct {
+		time.Duration
+	}
+)
+
+var (
+	cfg = defaultConfig()
+
+	// cfgPath is the path of the config file, FEEDA_CONFIG overrides it
+	cfgPath string
+
+	// cfgSources are where the settings came from by their names
+	cfgSources = make(map[string]string)
+
+	// envSettings are the settings that can be overridden by environment
+	// variables
+	envSettings = []struct {
+		name string
+		env  string
+		set  func(c *config, value string) error
+	}{
+		{"db", "FEEDA_DB", func(c *config, value string) error {
+			c.DB = value
+			return nil
+		}},
+		{"timeout", "FEEDA_TIMEOUT", func(c *config, value string) error {
+			return c.Timeout.UnmarshalText([]byte(value))
+		}},
+		{"user_agent", "FEEDA_USER_AGENT", func(c *config, value string) error {
+			c.UserAgent = value
+			return nil
+		}},
+		{"limit", "FEEDA_LIMIT", func(c *config, value string) error {
+			var err error
+			c.Limit, err = strconv.ParseInt(value, 10, 64)
+			return err
+		}},
+		{"download_dir", "FEEDA_DOWNLOAD_DIR", func(c *config, value string) error {
+			c.DownloadDir = value
+			return nil
+		}},
+		{"interval", "FEEDA_INTERVAL", func(c *config, value string) error {
+			return c.Interval.UnmarshalText([]byte(value))
+		}},
+		{"min_interval", "FEEDA_MIN_INTERVAL", func(c *config, value string) error {
+			return c.MinInterval.UnmarshalText([]byte(value))
+		}},
+		{"max_interval", "FEEDA_MAX_INTERVAL", func(c *config, value string) error {
+			return c.MaxInterval.UnmarshalText([]byte(value))
+		}},
+	}
+
+	// configSettings are the settings printed by config show in order
+	configSettings = []string{
+		"db",
+		"timeout",
+		"user_agent",
+		"limit",
+		"download_dir",
+		"interval",
+		"min_interval",
+		"max_interval",
+		"retention.older_than",
+		"retention.keep_per_feed",
+		"retention.read_only",
+	}
+)
+
+// defaultConfig returns the settings used when neither the config file, the
+// environment variables nor the flags set them
+func defaultConfig() config {
+	return config{
+		DB:          "~/.feeda/db.sqlite",
+		Timeout:     duration{10 * time.Second},
+		UserAgent:   "Feeda_feed_aggregator/1.0",
+		Limit:       10,
+		DownloadDir: "~/.feeda/downloads",
+		Interval:    duration{time.Hour},
+		MinInterval: duration{15 * time.Minute},
+		MaxInterval: duration{24 * time.Hour},
+	}
+}
+
+// UnmarshalText parses a duration such as "10s", "90d" or "2w"
+func (d *duration) UnmarshalText(text []byte) error {
+	var err error
+
+	d.Duration, err = parseAge(string(text))
+
+	return err
+}
+
+// initConfig reads the config file and applies the environment variables and
+// the --db flag. A missing config file is the same as an empty one.
+func initConfig() {
+	var err error
+
+	cfgPath = os.Getenv("FEEDA_CONFIG")
+	if cfgPath == "" {
+		var dir string
+		dir, err = feedaDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		cfgPath = path.Join(dir, configFile)
+	}
+
+	md, err := toml.DecodeFile(cfgPath, &cfg)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("could not read config file %s: %s", cfgPath, err)
+	}
+
+	for _, name := range configSettings {
+		cfgSources[name] = sourceDefault
+	}
+
+	for _, key := range md.Keys() {
+		cfgSources[key.String()] = sourceFile
+	}
+
+	for _, s := range envSettings {
+		value, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+
+		err = s.set(&cfg, value)
+		if err != nil {
+			log.Fatalf("invalid %s: %s", s.env, err)
+		}
+
+		cfgSources[s.name] = "env " + s.env
+	}
+
+	if dbPath != "" {
+		cfg.DB = dbPath
+		cfgSources["db"] = "flag --db"
+	}
+}
+
+// feedaDir returns the directory of the DB and config file, ~/.feeda
+func feedaDir() (string, error) {
+	dir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(dir, ".feeda"), nil
+}
+
+// feed returns the settings of the feed with the URL
+func (c config) feed(u string) feedConfig {
+	for _, f := range c.Feeds {
+		if f.URL == u {
+			return f
+		}
+	}
+
+	return feedConfig{}
+}
+
+// feedTimeout returns the HTTP timeout of the feed with the URL
+func (c config) feedTimeout(u string) time.Duration {
+	if t := c.feed(u).Timeout.Duration; t > 0 {
+		return t
+	}
+
+	return c.Timeout.Duration
+}
+
+// retentionPolicy returns the policy of the retention settings
+func (c config) retentionPolicy() sqlite.RetentionPolicy {
+	return sqlite.RetentionPolicy{
+		MaxAge:   c.Retention.OlderThan.Duration,
+		Keep:     c.Retention.KeepPerFeed,
+		ReadOnly: c.Retention.ReadOnly,
+	}
+}
+
+// configCmd is the parent of the commands about the config
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show the config",
+	Long: `Settings are read from ~/.feeda/config.toml, or the file given by the
+FEEDA_CONFIG environment variable, and overridden by environment variables and
+flags. Example of a config file:
+
+db = "~/feeds.sqlite"           # FEEDA_DB or --db
+timeout = "30s"                 # FEEDA_TIMEOUT
+user_agent = "My reader"        # FEEDA_USER_AGENT
+limit = 20                      # FEEDA_LIMIT, default of list --limit
+download_dir = "~/Podcasts"     # FEEDA_DOWNLOAD_DIR
+interval = "2h"                 # FEEDA_INTERVAL, how often new feeds are synced
+min_interval = "5m"             # FEEDA_MIN_INTERVAL
+max_interval = "1w"             # FEEDA_MAX_INTERVAL
+
+# Default of prune and policy of sync --prune
+[retention]
+older_than = "90d"
+keep_per_feed = 20
+read_only = true
+
+[templates]
+dmenu = "{{.ID}}\t{{.FeedTitle}}\t{{.Title}}"
+
+[[feeds]]
+url = "https://example.com/private.xml"
+timeout = "1m"
+interval = "15m"
+username = "me"
+password = "secret"
+headers = { X-Token = "secret" }`,
+}
+
+// configShowCmd prints the effective settings
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the effective settings",
+	Long:  `Prints the effective settings and where each one came from`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("# Config file: %s\n", cfgPath)
+
+		values := map[string]interface{}{
+			"db":                      cfg.DB,
+			"timeout":                 formatAge(cfg.Timeout.Duration),
+			"user_agent":              cfg.UserAgent,
+			"limit":                   cfg.Limit,
+			"download_dir":            cfg.DownloadDir,
+			"interval":                formatAge(cfg.Interval.Duration),
+			"min_interval":            formatAge(cfg.MinInterval.Duration),
+			"max_interval":            formatAge(cfg.MaxInterval.Duration),
+			"retention.older_than":    formatAge(cfg.Retention.OlderThan.Duration),
+			"retention.keep_per_feed": cfg.Retention.KeepPerFeed,
+			"retention.read_only":     cfg.Retention.ReadOnly,
+		}
+
+		for _, name := range configSettings {
+			printSetting(name, values[name], cfgSources[name])
+		}
+
+		var names []string
+		for name := range cfg.Templates {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			printSetting("templates."+name, cfg.Templates[name], sourceFile)
+		}
+
+		for _, f := range cfg.Feeds {
+			prefix := fmt.Sprintf("feeds[%s].", strconv.Quote(f.URL))
+
+			if f.Timeout.Duration > 0 {
+				printSetting(prefix+"timeout", formatAge(f.Timeout.Duration), sourceFile)
+			}
+			if f.Interval.Duration > 0 {
+				printSetting(prefix+"interval", formatAge(f.Interval.Duration), sourceFile)
+			}
+			var headers []string
+			for header := range f.Headers {
+				headers = append(headers, header)
+			}
+
+			sort.Strings(headers)
+			for _, header := range headers {
+				// Headers usually hold tokens
+				printSetting(prefix+"headers."+header, "********", sourceFile)
+			}
+			if f.Username != "" {
+				printSetting(prefix+"username", f.Username, sourceFile)
+			}
+			if f.Password != "" {
+				printSetting(prefix+"password", "********", sourceFile)
+			}
+			if f.FullContent {
+				printSetting(prefix+"full_content", f.FullContent, sourceFile)
+			}
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	RootCmd.AddCommand(configCmd)
+}
+
+// printSetting prints a setting as TOML with where it came from as a comment
+func printSetting(name string, value interface{}, source string) {
+	if s, ok := value.(string); ok {
+		value = strconv.Quote(s)
+	}
+
+	fmt.Printf("%s = %v # %s\n", name, value, source)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestInitConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfgFile := path.Join(dir, configFile)
+	err = ioutil.WriteFile(cfgFile, []byte(`
+timeout = "30s"
+limit = 20
+
+[retention]
+older_than = "2w"
+
+[[feeds]]
+url = "https://example.com/feed.xml"
+timeout = "1m"
+headers = { X-Token = "secret" }
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("FEEDA_CONFIG", cfgFile)
+	os.Setenv("FEEDA_LIMIT", "5")
+	defer os.Unsetenv("FEEDA_CONFIG")
+	defer os.Unsetenv("FEEDA_LIMIT")
+
+	initConfig()
+
+	if cfg.Timeout.Duration != 30*time.Second || cfgSources["timeout"] != sourceFile {
+		t.Fatalf("expecting timeout 30s from the config file, got %s from %s", cfg.Timeout, cfgSources["timeout"])
+	}
+
+	if cfg.Limit != 5 || cfgSources["limit"] != "env FEEDA_LIMIT" {
+		t.Fatalf("expecting limit 5 from FEEDA_LIMIT, got %d from %s", cfg.Limit, cfgSources["limit"])
+	}
+
+	if cfg.UserAgent != defaultConfig().UserAgent || cfgSources["user_agent"] != sourceDefault {
+		t.Fatalf("expecting default user agent, got %q from %s", cfg.UserAgent, cfgSources["user_agent"])
+	}
+
+	if cfg.retentionPolicy().MaxAge != 14*24*time.Hour {
+		t.Fatalf("expecting retention of 2 weeks, got %s", cfg.retentionPolicy().MaxAge)
+	}
+
+	if cfg.feedTimeout("https://example.com/feed.xml") != time.Minute {
+		t.Fatalf("expecting feed timeout 1m, got %s", cfg.feedTimeout("https://example.com/feed.xml"))
+	}
+
+	if cfg.feedTimeout("https://example.com/other.xml") != 30*time.Second {
+		t.Fatalf("expecting default timeout for other feeds, got %s", cfg.feedTimeout("https://example.com/other.xml"))
+	}
+
+	if cfg.feed("https://example.com/feed.xml").Headers["X-Token"] != "secret" {
+		t.Fatalf("expecting header of feed, got %v", cfg.feed("https://example.com/feed.xml").Headers)
+	}
+}
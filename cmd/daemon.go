@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"feeda/sqlite"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// daemonPollInterval is the longest time the daemon sleeps so feeds
+	// added while it is running are picked up
+	daemonPollInterval = time.Minute
+)
+
+var (
+	daemonTag *string
+)
+
+// daemonCmd keeps running and syncs each feed when it is due
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep syncing feeds in the background",
+	Long: `Keeps running and syncs each feed on its own interval instead of syncing all
+feeds at once. Feeds are synced as often as new items are found in them on
+average, but not more often than their <ttl>, their <sy:updatePeriod> or the
+Cache-Control or Expires headers of their responses allow. The intervals are
+bounded by the min_interval and max_interval settings and the interval of a
+feed in the config file overrides it. Feeds failing to sync are retried with
+exponential backoff.
+
+Only one daemon can run per DB, the PID of the daemon is written to a lock file
+next to the DB. The daemon finishes the syncs in progress and exits on SIGTERM
+or SIGINT. Example:
+
+# Sync feeds in the background
+feeda daemon &
+
+# Sync only feeds tagged with "news"
+feeda daemon --tag=news`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := homedir.Expand(cfg.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		unlock, err := lockFile(p + ".lock")
+		if err != nil {
+			log.Fatalf("could not start daemon: %s", err)
+		}
+		defer unlock()
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+
+		log.Printf("daemon started with PID %d", os.Getpid())
+		runDaemon(stop)
+		log.Print("daemon stopped")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(daemonCmd)
+
+	daemonTag = daemonCmd.Flags().StringP("tag", "t", "", "Sync only feeds with the tag")
+}
+
+// runDaemon syncs feeds when they are due until a signal is received, the
+// syncs in progress are finished before returning
+func runDaemon(stop <-chan os.Signal) {
+	c := newHTTPClient()
+	syncing := make(map[int64]bool)
+	done := make(chan int64)
+
+	for {
+		wake := time.Now().Add(daemonPollInterval)
+
+		feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{Tag: *daemonTag})
+		if err != nil {
+			log.Printf("could not list feeds: %s", err)
+		}
+
+		for _, feed := range feeds {
+			if syncing[feed.ID] {
+				continue
+			}
+
+			next := nextSync(*feed)
+			if next.After(time.Now()) {
+				if next.Before(wake) {
+					wake = next
+				}
+
+				continue
+			}
+
+			syncing[feed.ID] = true
+
+			go func(feed sqlite.Feed) {
+				daemonSyncFeed(c, feed)
+				done <- feed.ID
+			}(*feed)
+		}
+
+		select {
+		case id := <-done:
+			delete(syncing, id)
+		case <-time.After(time.Until(wake)):
+		case sig := <-stop:
+			log.Printf("received %s, waiting for %d syncs to finish", sig, len(syncing))
+
+			for len(syncing) > 0 {
+				delete(syncing, <-done)
+			}
+
+			return
+		}
+	}
+}
+
+// daemonSyncFeed syncs a feed and records the result, the next sync of the
+// feed is scheduled by its synced_at or its errors
+func daemonSyncFeed(c *http.Client, feed sqlite.Feed) {
+	upserted, modified, err := syncFeed(c, feed)
+	if err != nil {
+		log.Printf("%d. could not sync %s: %s", feed.ID, feed.URL, err)
+
+		err = sqlite.SetFeedError(db, feed.ID, err.Error())
+		if err != nil {
+			log.Printf("%d. could not record error: %s", feed.ID, err)
+		}
+
+		return
+	}
+
+	err = sqlite.SetFeedsSyncedAtNow(db, feed.ID)
+	if err != nil {
+		log.Printf("%d. could not record sync: %s", feed.ID, err)
+		return
+	}
+
+	if modified {
+		log.Printf("%d. %d items added or updated", feed.ID, upserted)
+	} else {
+		log.Printf("%d. not modified", feed.ID)
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// dateLayouts are the formats of dates seen in real feeds. Dates are
+	// normalized by parseDate before being parsed so the layouts don't need
+	// to cover weekdays or named time zones.
+	dateLayouts = []string{
+		// RFC822 and RFC1123 with four or two digit years, with or without seconds
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04 -0700",
+		"2 Jan 06 15:04:05 -0700",
+		"2 Jan 06 15:04 -0700",
+		"2 Jan 2006 15:04:05 MST",
+		"2 Jan 2006 15:04 MST",
+		"2 Jan 06 15:04:05 MST",
+		"2 Jan 06 15:04 MST",
+		"2 Jan 2006 15:04:05",
+		"2 Jan 2006 15:04",
+		// ISO8601/W3C-DTF as used by Atom, JSON Feed and dc:date
+		time.RFC3339,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+
+	// namedZones maps time zone abbreviations used in feeds to their offsets,
+	// time.Parse only knows the offset of the local time zone
+	namedZones = map[string]string{
+		"UT":   "+0000",
+		"UTC":  "+0000",
+		"GMT":  "+0000",
+		"Z":    "+0000",
+		"EST":  "-0500",
+		"EDT":  "-0400",
+		"CST":  "-0600",
+		"CDT":  "-0500",
+		"MST":  "-0700",
+		"MDT":  "-0600",
+		"PST":  "-0800",
+		"PDT":  "-0700",
+		"CET":  "+0100",
+		"CEST": "+0200",
+	}
+
+	weekdayPrefix = regexp.MustCompile(`^[A-Za-z]+,\s*`)
+	namedZone     = regexp.MustCompile(`\s([A-Za-z]{1,5})$`)
+	whitespaces   = regexp.MustCompile(`\s+`)
+)
+
+// parseDate parses dates of items in any of the formats seen in real feeds
+func parseDate(s string) (time.Time, error) {
+	value := whitespaces.ReplaceAllString(strings.TrimSpace(s), " ")
+	value = weekdayPrefix.ReplaceAllString(value, "")
+	value = namedZone.ReplaceAllStringFunc(value, func(zone string) string {
+		if offset, ok := namedZones[strings.ToUpper(strings.TrimSpace(zone))]; ok {
+			return " " + offset
+		}
+
+		return zone
+	})
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format %q", s)
+}
+
+// parseAge parses an age in days such as "90d", weeks such as "2w" or in any
+// unit of time.ParseDuration such as "36h"
+func parseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+
+	value := strings.TrimSpace(s)
+	switch {
+	case strings.HasSuffix(value, "d"), strings.HasSuffix(value, "w"):
+		var n int64
+		n, err = strconv.ParseInt(value[:len(value)-1], 10, 64)
+		d = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(value, "w") {
+			d *= 7
+		}
+	default:
+		d, err = time.ParseDuration(value)
+	}
+
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q, use days such as 90d, weeks such as 2w or hours such as 36h", s)
+	}
+
+	return d, nil
+}
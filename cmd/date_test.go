@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 2 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 06 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04:05 EST", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC)},
+		{"Mon, 02 Jan 2006 15:04 PDT", time.Date(2006, 1, 2, 22, 4, 0, 0, time.UTC)},
+		{"02 Jan 2006 15:04:05 +0100", time.Date(2006, 1, 2, 14, 4, 5, 0, time.UTC)},
+		{"Monday,  2 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02T15:04:05.123+02:00", time.Date(2006, 1, 2, 13, 4, 5, 123000000, time.UTC)},
+		{"2006-01-02T15:04+02:00", time.Date(2006, 1, 2, 13, 4, 0, 0, time.UTC)},
+		{"2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		actual, err := parseDate(test.value)
+		if err != nil {
+			t.Fatalf("could not parse %q: %s", test.value, err)
+		}
+		if !actual.Equal(test.expected) {
+			t.Fatalf("expecting %q to be parsed as %s, got %s", test.value, test.expected, actual.UTC())
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "02/01/2006"} {
+		_, err := parseDate(value)
+		if err == nil {
+			t.Fatalf("expecting %q to fail", value)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+		{" 0d ", 0},
+	}
+
+	for _, test := range tests {
+		actual, err := parseAge(test.value)
+		if err != nil {
+			t.Fatalf("could not parse %q: %s", test.value, err)
+		}
+		if actual != test.expected {
+			t.Fatalf("expecting %q to be parsed as %s, got %s", test.value, test.expected, actual)
+		}
+	}
+
+	for _, value := range []string{"", "d", "90", "-2d", "1y", "soon"} {
+		_, err := parseAge(value)
+		if err == nil {
+			t.Fatalf("expecting %q to fail", value)
+		}
+	}
+}
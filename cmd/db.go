@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var dryRun *bool
+
+// dbCmd is the parent of the commands managing the DB
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the DB",
+	Long:  `Manages the SQLite database of feeds and items`,
+}
+
+// dbMigrateCmd upgrades the schema of the DB
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the DB schema",
+	Long: `Upgrades the schema of the DB to the latest version. The schema is also
+upgraded automatically by all other commands. Example:
+
+# Show the migrations that would be run
+feeda db migrate --dry-run`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := sqlite.SchemaVersion(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var migrations []sqlite.Migration
+		if *dryRun {
+			migrations, err = sqlite.PendingMigrations(db)
+		} else {
+			migrations, err = sqlite.Migrate(db)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(migrations) == 0 {
+			fmt.Printf("DB schema is up to date at version %d\n", version)
+			return
+		}
+
+		if *dryRun {
+			fmt.Printf("DB schema is at version %d, pending migrations:\n", version)
+		} else {
+			fmt.Printf("DB schema migrated from version %d:\n", version)
+		}
+
+		for _, m := range migrations {
+			fmt.Printf("%d. %s\n", m.Version, m.Description)
+		}
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbMigrateCmd)
+	RootCmd.AddCommand(dbCmd)
+
+	dryRun = dbMigrateCmd.Flags().Bool("dry-run", false, "Show the pending migrations without running them")
+}
@@ -9,11 +9,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	forceDelete *bool
+)
+
 // deleteCmd deletes one or more items from DB
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete items",
-	Long:  `Deletes one or more items from DB`,
+	Long: `Deletes one or more items from DB. Starred items are kept unless
+--force is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		var ids []int64
 
@@ -26,13 +31,33 @@ var deleteCmd = &cobra.Command{
 			ids = append(ids, id)
 		}
 
+		if *forceDelete {
+			err := sqlite.ForceDeleteItems(db, ids...)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+
 		err := sqlite.DeleteItems(db, ids...)
 		if err != nil {
 			log.Fatal(err)
 		}
+
+		kept, err := sqlite.ListItems(db, sqlite.ItemFilter{IDs: ids, StarStatus: sqlite.ItemStarred})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, item := range kept {
+			log.Printf("%d. kept as it is starred, use --force to delete it", item.ID)
+		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(deleteCmd)
+
+	forceDelete = deleteCmd.Flags().Bool("force", false, "Delete starred items too")
 }
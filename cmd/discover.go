@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"feeda/sqlite"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// feedMIMETypes are the types of <link rel="alternate"> elements pointing to feeds
+	feedMIMETypes = []string{
+		"application/rss+xml",
+		"application/atom+xml",
+		"application/rdf+xml",
+		"application/feed+json",
+	}
+
+	// commonFeedPaths are tried when a HTML page doesn't advertise any feeds
+	commonFeedPaths = []string{
+		"/feed",
+		"/rss",
+		"/feed.xml",
+		"/rss.xml",
+		"/atom.xml",
+		"/index.xml",
+		"/feed.json",
+	}
+)
+
+// fetch downloads the content of the URL
+func fetch(c *http.Client, u string) (*http.Response, []byte, error) {
+	req, err := newRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := feedHTTPClient(c, u).Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch URL %s: %v", u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, nil, fmt.Errorf("unexpected status %s for URL %s", resp.Status, u)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	return resp, body, err
+}
+
+// discoverFeeds returns the feed at the URL or, if the URL is a HTML page, the
+// feeds advertised by it. If the page doesn't advertise any feeds then the
+// common paths of feeds are tried.
+func discoverFeeds(c *http.Client, u string) ([]sqlite.Feed, error) {
+	resp, body, err := fetch(c, u)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, err := newFeed(u, body)
+	if err == nil {
+		return []sqlite.Feed{feed}, nil
+	}
+
+	if !isHTML(resp, body) {
+		return nil, err
+	}
+
+	// Relative links are resolved against the URL after redirects
+	base := resp.Request.URL
+	links := feedLinks(body, base)
+
+	var feeds []sqlite.Feed
+	for _, link := range links {
+		_, body, err := fetch(c, link)
+		if err != nil {
+			log.Printf("skipping advertised feed: %v", err)
+			continue
+		}
+
+		feed, err := newFeed(link, body)
+		if err != nil {
+			log.Printf("skipping advertised feed %s: %v", link, err)
+			continue
+		}
+
+		feeds = append(feeds, feed)
+	}
+
+	if len(links) == 0 {
+		for _, p := range commonFeedPaths {
+			link := base.ResolveReference(&url.URL{Path: p}).String()
+
+			_, body, err := fetch(c, link)
+			if err != nil {
+				continue
+			}
+
+			feed, err := newFeed(link, body)
+			if err != nil {
+				continue
+			}
+
+			feeds = append(feeds, feed)
+			break
+		}
+	}
+
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("could not find any feeds at URL %s", u)
+	}
+
+	return feeds, nil
+}
+
+// isHTML returns true if the response is a HTML page
+func isHTML(resp *http.Response, body []byte) bool {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	return strings.Contains(contentType, "html")
+}
+
+// feedLinks returns the absolute URLs of the feeds advertised by a HTML page
+// with <link rel="alternate"> elements
+func feedLinks(body []byte, base *url.URL) []string {
+	var links []string
+	seen := make(map[string]bool)
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return links
+		}
+
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		t := z.Token()
+		if t.Data == "body" {
+			return links
+		}
+
+		if t.Data != "link" && t.Data != "base" {
+			continue
+		}
+
+		attrs := make(map[string]string)
+		for _, attr := range t.Attr {
+			attrs[strings.ToLower(attr.Key)] = strings.TrimSpace(attr.Val)
+		}
+
+		href, err := url.Parse(attrs["href"])
+		if err != nil || attrs["href"] == "" {
+			continue
+		}
+
+		if t.Data == "base" {
+			base = base.ResolveReference(href)
+			continue
+		}
+
+		if !hasToken(attrs["rel"], "alternate") || !isFeedMIMEType(attrs["type"]) {
+			continue
+		}
+
+		link := base.ResolveReference(href).String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+}
+
+// hasToken returns true if the space separated list contains the token
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isFeedMIMEType(t string) bool {
+	t = strings.ToLower(strings.TrimSpace(strings.Split(t, ";")[0]))
+	for _, feedType := range feedMIMETypes {
+		if t == feedType {
+			return true
+		}
+	}
+
+	return false
+}
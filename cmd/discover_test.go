@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"feeda/sqlite"
+)
+
+const testRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title></channel></rss>`
+
+const testAtom = `<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title></feed>`
+
+func TestFeedLinks(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	tests := []struct {
+		page     string
+		expected []string
+	}{
+		{`<html><head></head><body></body></html>`, nil},
+		{
+			`<link rel="alternate" type="application/rss+xml" href="/feed.xml">`,
+			[]string{"https://example.com/feed.xml"},
+		},
+		{
+			`<link rel="Alternate home" type="application/atom+xml; charset=utf-8" href="atom.xml">` +
+				`<link rel="alternate" type="application/feed+json" href="https://feeds.example.org/blog.json">`,
+			[]string{"https://example.com/blog/atom.xml", "https://feeds.example.org/blog.json"},
+		},
+		{
+			`<base href="https://cdn.example.com/site/"><link rel="alternate" type="application/rdf+xml" href="index.rdf">`,
+			[]string{"https://cdn.example.com/site/index.rdf"},
+		},
+		{
+			`<link rel="alternate" type="application/rss+xml" href="/feed">` +
+				`<link rel="alternate" type="application/rss+xml" href="https://example.com/feed">`,
+			[]string{"https://example.com/feed"},
+		},
+		{
+			`<link rel="stylesheet" type="text/css" href="/style.css">` +
+				`<link rel="alternate" type="text/html" href="/fr/">` +
+				`<link rel="alternate" type="application/rss+xml" href="">`,
+			nil,
+		},
+		{
+			`<head></head><body><link rel="alternate" type="application/rss+xml" href="/feed"></body>`,
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		actual := feedLinks([]byte(test.page), base)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestPickFeeds(t *testing.T) {
+	feeds := []sqlite.Feed{{URL: "a"}, {URL: "b"}, {URL: "c"}}
+
+	tests := []struct {
+		feeds    []sqlite.Feed
+		picks    []int
+		expected []sqlite.Feed
+		err      bool
+	}{
+		{feeds[:1], nil, feeds[:1], false},
+		{feeds[:1], []int{2}, feeds[:1], false},
+		{feeds, nil, feeds, true},
+		{feeds, []int{3, 1}, []sqlite.Feed{{URL: "c"}, {URL: "a"}}, false},
+		{feeds, []int{4}, feeds, true},
+		{feeds, []int{0}, feeds, true},
+	}
+
+	for i, test := range tests {
+		actual, err := pickFeeds(test.feeds, test.picks)
+		if (err != nil) != test.err {
+			t.Fatalf("%d: expecting error %t, got %v", i, test.err, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %v, got %v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestDiscoverFeeds(t *testing.T) {
+	const htmlType, xmlType = "text/html; charset=utf-8", "application/xml"
+
+	pages := map[string]struct {
+		contentType, body string
+	}{
+		"/posts.xml": {xmlType, testRSS},
+		"/atom.xml":  {xmlType, testAtom},
+		"/rss.xml":   {xmlType, testRSS},
+		"/broken":    {xmlType, `<rss><channel>`},
+		"/advertise": {htmlType, `<link rel="alternate" type="application/rss+xml" href="/posts.xml"><link rel="alternate" type="application/atom+xml" href="/atom.xml">`},
+		"/dead-link": {htmlType, `<link rel="alternate" type="application/rss+xml" href="/missing.xml"><link rel="alternate" type="application/atom+xml" href="/atom.xml">`},
+		"/plain":     {htmlType, `<html><head><title>No feeds</title></head></html>`},
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write([]byte(page.body))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"/posts.xml", []string{"/posts.xml"}},
+		{"/advertise", []string{"/posts.xml", "/atom.xml"}},
+		{"/dead-link", []string{"/atom.xml"}},
+		// Pages without advertised feeds fall back to the first common path
+		// that is a feed
+		{"/plain", []string{"/rss.xml"}},
+		{"/broken", nil},
+		{"/missing", nil},
+	}
+
+	for i, test := range tests {
+		feeds, err := discoverFeeds(srv.Client(), srv.URL+test.path)
+		if test.expected == nil {
+			if err == nil {
+				t.Fatalf("%d: expecting an error, got %v", i, feeds)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+
+		var actual []string
+		for _, feed := range feeds {
+			actual = append(actual, feed.URL[len(srv.URL):])
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+}
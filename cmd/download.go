@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"feeda/sqlite"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+var (
+	downloadDir     *string
+	concurrency     *int
+	forceDownload   *bool
+	unsafeFileChars = regexp.MustCompile(`[^\w.-]+`)
+)
+
+// downloadCmd downloads the enclosures of items
+var downloadCmd = &cobra.Command{
+	Use:   "download [item IDs]",
+	Short: "Download enclosures of items",
+	Long: `Downloads the enclosures, such as podcast episodes, of items. Calling this
+command without any arguments will download all enclosures that haven't been
+downloaded yet. Interrupted downloads are resumed. Example:
+
+# Download the enclosures of items with ID = 4 and ID = 7
+feeda download 4 7
+
+# Download all enclosures, 4 at a time, to ~/Podcasts
+feeda download --concurrency=4 --dir=~/Podcasts`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var ids []int64
+
+		for _, arg := range args {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		if *concurrency < 1 {
+			log.Fatal("concurrency must be at least 1")
+		}
+
+		if !cmd.Flags().Changed("dir") {
+			*downloadDir = cfg.DownloadDir
+		}
+
+		dir, err := homedir.Expand(*downloadDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = os.MkdirAll(dir, os.ModePerm)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		enclosures, err := sqlite.ListEnclosures(db, sqlite.EnclosureFilter{
+			ItemIDs:       ids,
+			NotDownloaded: !*forceDownload,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c := newDownloadClient()
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var failed int
+		sem := make(chan struct{}, *concurrency)
+
+		for _, enc := range enclosures {
+			wg.Add(1)
+
+			go func(enc sqlite.Enclosure) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				dst, err := downloadEnclosure(c, enc, dir)
+				if err == nil {
+					err = sqlite.SetEnclosureDownloadedNow(db, enc.ID, dst)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					log.Printf("%d. could not download %s: %s", enc.ItemID, enc.URL, err)
+					failed++
+					return
+				}
+
+				fmt.Printf("%d. downloaded %s\n", enc.ItemID, dst)
+			}(*enc)
+		}
+
+		wg.Wait()
+
+		if failed > 0 {
+			log.Fatalf("%d of %d enclosures failed to download", failed, len(enclosures))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(downloadCmd)
+
+	downloadDir = downloadCmd.Flags().StringP("dir", "d", "", "Directory to download enclosures to, defaults to the download_dir setting")
+	concurrency = downloadCmd.Flags().IntP("concurrency", "c", 2, "Number of enclosures to download at the same time")
+	forceDownload = downloadCmd.Flags().Bool("force", false, "Download enclosures again even if they have been downloaded")
+}
+
+// downloadEnclosure downloads an enclosure to the directory and returns the
+// path of the downloaded file. The file is downloaded to a .part file first
+// which is resumed if it exists.
+func downloadEnclosure(c *http.Client, enc sqlite.Enclosure, dir string) (string, error) {
+	dst := filepath.Join(dir, enclosureFileName(enc))
+	part := dst + ".part"
+
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := newRequest(http.MethodGet, enc.URL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		// Resume where the previous download stopped
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The previous download was complete but not renamed
+		f.Close()
+
+		return dst, os.Rename(part, dst)
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		// Server doesn't support ranges so start over
+		_, err = f.Seek(0, io.SeekStart)
+		if err == nil {
+			err = f.Truncate(0)
+		}
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	_, err = io.Copy(f, resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	return dst, os.Rename(part, dst)
+}
+
+// enclosureFileName returns the name of the file to download an enclosure to,
+// prefixed by the ID of the enclosure to make it unique
+func enclosureFileName(enc sqlite.Enclosure) string {
+	name := "enclosure"
+
+	u, err := url.Parse(enc.URL)
+	if err == nil {
+		if base := path.Base(u.Path); base != "." && base != "/" {
+			name = base
+		}
+	}
+
+	name = strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "._")
+	if name == "" {
+		name = "enclosure"
+	}
+
+	return fmt.Sprintf("%d-%s", enc.ID, name)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+// exportCmd is the parent of the commands exporting feeds to other readers
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export feeds",
+	Long:  `Exports feeds to be imported by other feed readers`,
+}
+
+// exportOPMLCmd writes all feeds as OPML to stdout
+var exportOPMLCmd = &cobra.Command{
+	Use:   "opml",
+	Short: "Export feeds as OPML",
+	Long: `Writes all feeds as an OPML 2.0 document to stdout. Tagged feeds are
+nested in an outline per tag. Example:
+
+# Export feeds to a file
+feeda export opml > feeds.opml`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = writeOPML(os.Stdout, newOPML(feeds, time.Now()))
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	exportCmd.AddCommand(exportOPMLCmd)
+	RootCmd.AddCommand(exportCmd)
+}
+
+// newOPML returns an OPML document of the feeds where tagged feeds are nested
+// in an outline per tag
+func newOPML(feeds []*sqlite.Feed, created time.Time) opml {
+	content := opml{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "Feeda subscriptions",
+			DateCreated: created.Format(time.RFC1123Z),
+		},
+	}
+
+	// Feeds with many tags are repeated in the outline of each tag
+	var tags []string
+	tagged := make(map[string][]opmlOutline)
+
+	for _, feed := range feeds {
+		title := firstNonEmpty(feed.Title, feed.URL)
+		outline := opmlOutline{
+			Text:    title,
+			Title:   title,
+			Type:    strings.ToLower(string(feed.Type)),
+			XMLURL:  feed.URL,
+			HTMLURL: feed.Link,
+		}
+
+		if len(feed.Tags) == 0 {
+			content.Body.Outlines = append(content.Body.Outlines, outline)
+			continue
+		}
+
+		for _, tag := range feed.Tags {
+			if _, ok := tagged[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			tagged[tag] = append(tagged[tag], outline)
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	for _, tag := range tags {
+		content.Body.Outlines = append(content.Body.Outlines, opmlOutline{
+			Text:     tag,
+			Title:    tag,
+			Outlines: tagged[tag],
+		})
+	}
+
+	return content
+}
+
+// writeOPML writes an OPML document with its XML header
+func writeOPML(w io.Writer, content opml) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	err = e.Encode(content)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
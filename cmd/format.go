@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"feeda/sqlite"
+)
+
+// formatSize formats a number of bytes in a human readable way
+func formatSize(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats a number of seconds as H:MM:SS or M:SS
+func formatDuration(seconds int64) string {
+	h, m, s := seconds/3600, seconds%3600/60, seconds%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// formatEnclosure formats an enclosure as its URL followed by its type, size,
+// duration and where it has been downloaded to when known
+func formatEnclosure(enc *sqlite.Enclosure) string {
+	var attrs []string
+
+	if enc.MIMEType != "" {
+		attrs = append(attrs, enc.MIMEType)
+	}
+	if enc.Length > 0 {
+		attrs = append(attrs, formatSize(enc.Length))
+	}
+	if enc.Duration > 0 {
+		attrs = append(attrs, formatDuration(enc.Duration))
+	}
+	if enc.DownloadedAt != nil {
+		attrs = append(attrs, fmt.Sprintf("Downloaded to %s", enc.Path))
+	}
+
+	if len(attrs) == 0 {
+		return enc.URL
+	}
+
+	return fmt.Sprintf("%s (%s)", enc.URL, strings.Join(attrs, ", "))
+}
+
+// formatAge formats an age in whole weeks, days, hours or minutes when
+// possible, the reverse of parseAge
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+
+	switch {
+	case d == 0:
+		return "0d"
+	case d%(7*day) == 0:
+		return fmt.Sprintf("%dw", d/(7*day))
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+
+	return d.String()
+}
+
+// formatRetention formats the retention of a feed, returns an empty string if
+// the feed uses the default retention
+func formatRetention(feed *sqlite.Feed) string {
+	var attrs []string
+
+	if feed.RetentionMaxAge != nil {
+		if *feed.RetentionMaxAge == 0 {
+			attrs = append(attrs, "any age")
+		} else {
+			attrs = append(attrs, fmt.Sprintf("older than %s", formatAge(*feed.RetentionMaxAge)))
+		}
+	}
+	if feed.RetentionKeep != nil {
+		attrs = append(attrs, fmt.Sprintf("keep %d", *feed.RetentionKeep))
+	}
+
+	return strings.Join(attrs, ", ")
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newHTTPClient returns a client with the timeout of the config
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: cfg.Timeout.Duration,
+	}
+}
+
+// newDownloadClient returns a client for downloads that can take long, such as
+// media files. Connecting and waiting for the response headers are limited by
+// the timeout of the config but reading the body isn't.
+func newDownloadClient() *http.Client {
+	timeout := cfg.Timeout.Duration
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	t.TLSHandshakeTimeout = timeout
+	t.ResponseHeaderTimeout = timeout
+
+	c := newHTTPClient()
+	c.Transport = t
+	c.Timeout = 0
+
+	return c
+}
+
+// feedHTTPClient returns a copy of the client with the timeout of the feed
+// with the URL
+func feedHTTPClient(c *http.Client, u string) *http.Client {
+	fc := *c
+	fc.Timeout = cfg.feedTimeout(u)
+
+	return &fc
+}
+
+// newRequest returns a request with the user agent of the config and the
+// headers and credentials of the feed with the URL
+func newRequest(method, u string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", cfg.UserAgent)
+
+	f := cfg.feed(u)
+	for k, v := range f.Headers {
+		req.Header.Set(k, v)
+	}
+
+	if f.Username != "" || f.Password != "" {
+		req.SetBasicAuth(f.Username, f.Password)
+	}
+
+	return req, nil
+}
+
+// cacheInterval returns how long the response can be cached by its
+// Cache-Control max-age or its Expires header, 0 if it can't be cached
+func cacheInterval(resp *http.Response) time.Duration {
+	for _, directive := range strings.Split(resp.Header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(directive, "max-age="), 10, 64)
+			if err != nil || seconds < 0 {
+				return 0
+			}
+
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	expires, err := http.ParseTime(resp.Header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+
+	// Expires is relative to the clock of the server
+	now, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		now = time.Now()
+	}
+
+	if expires.Before(now) {
+		return 0
+	}
+
+	return expires.Sub(now)
+}
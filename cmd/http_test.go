@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDownloadClient(t *testing.T) {
+	timeout := cfg.Timeout
+	defer func() { cfg.Timeout = timeout }()
+	cfg.Timeout.Duration = 100 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(300 * time.Millisecond)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		// The body takes longer than the timeout
+		for i := 0; i < 3; i++ {
+			time.Sleep(60 * time.Millisecond)
+			w.Write([]byte("data"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+
+	c := newDownloadClient()
+
+	resp, err := c.Get(srv.URL + "/slow-body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("expecting slow body to be read, got %s", err)
+	}
+	if string(body) != "datadatadata" {
+		t.Fatalf("expecting %q, got %q", "datadatadata", body)
+	}
+
+	_, err = c.Get(srv.URL + "/slow-headers")
+	if err == nil {
+		t.Fatal("expecting slow response headers to time out")
+	}
+}
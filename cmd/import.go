@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+// maxConcurrentImports limits the number of feeds fetched at the same time
+// when detecting the types of imported feeds
+const maxConcurrentImports = 10
+
+// importCmd is the parent of the commands importing feeds from other readers
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import feeds",
+	Long:  `Imports feeds exported from other feed readers`,
+}
+
+// importOPMLCmd adds the feeds in an OPML file to the DB
+var importOPMLCmd = &cobra.Command{
+	Use:   "opml [file]",
+	Short: "Import feeds from an OPML file",
+	Long: `Adds all feeds in an OPML file to aggregate. Each feed is fetched to
+detect its type, if that fails then the type attribute of the outline is used.
+Feeds nested in folders are tagged with the names of the folders.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		var content opml
+		err = xml.NewDecoder(f).Decode(&content)
+		if err != nil {
+			log.Fatalf("could not read OPML file %s: %s", args[0], err)
+		}
+
+		outlines := flattenOutlines(content.Body.Outlines, nil)
+		if len(outlines) == 0 {
+			log.Fatalf("could not find any feeds in OPML file %s", args[0])
+		}
+
+		c := newHTTPClient()
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var feeds []sqlite.Feed
+		sem := make(chan struct{}, maxConcurrentImports)
+
+		for _, outline := range outlines {
+			wg.Add(1)
+			go func(outline opmlOutline) {
+				defer wg.Done()
+
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				feed := newOPMLFeed(outline)
+
+				_, body, err := fetch(c, outline.XMLURL)
+				if err == nil {
+					var detected sqlite.Feed
+					detected, err = newFeed(outline.XMLURL, body)
+					if err == nil {
+						feed = detected
+					}
+				}
+				if err != nil {
+					log.Printf("could not detect type of %s, using %s: %v", outline.XMLURL, feed.Type, err)
+				}
+
+				mu.Lock()
+				feeds = append(feeds, feed)
+				mu.Unlock()
+			}(outline)
+		}
+
+		wg.Wait()
+
+		err = sqlite.CreateIgnoreFeeds(db, feeds...)
+		if err != nil {
+			log.Fatal("could not add feeds:", err)
+		}
+
+		err = tagImportedFeeds(outlines)
+		if err != nil {
+			log.Fatal("could not tag feeds:", err)
+		}
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importOPMLCmd)
+	RootCmd.AddCommand(importCmd)
+}
+
+// flattenOutlines returns the outlines of feeds, nested outlines included.
+// Outlines nested in folders are tagged with the texts of the folders.
+func flattenOutlines(outlines []opmlOutline, tags []string) []opmlOutline {
+	var flattened []opmlOutline
+
+	for _, outline := range outlines {
+		outline.XMLURL = strings.TrimSpace(outline.XMLURL)
+		outline.Tags = tags
+		if outline.XMLURL != "" {
+			flattened = append(flattened, outline)
+		}
+
+		nestedTags := tags
+		if folder := firstNonEmpty(outline.Text, outline.Title); outline.XMLURL == "" && folder != "" {
+			// Tags can't contain commas
+			nestedTags = append(append([]string{}, tags...), strings.Replace(folder, ",", " ", -1))
+		}
+
+		flattened = append(flattened, flattenOutlines(outline.Outlines, nestedTags)...)
+	}
+
+	return flattened
+}
+
+// tagImportedFeeds tags the feeds of the outlines with their tags
+func tagImportedFeeds(outlines []opmlOutline) error {
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]int64)
+	for _, feed := range feeds {
+		ids[feed.URL] = feed.ID
+	}
+
+	tagged := make(map[string][]int64)
+	for _, outline := range outlines {
+		id, ok := ids[outline.XMLURL]
+		if !ok {
+			continue
+		}
+
+		for _, tag := range outline.Tags {
+			tagged[tag] = append(tagged[tag], id)
+		}
+	}
+
+	for tag, feedIDs := range tagged {
+		err = sqlite.TagFeeds(db, tag, feedIDs...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newOPMLFeed creates a feed from an outline with the type taken from the
+// type attribute, OPML files usually use "rss" for all kinds of feeds
+func newOPMLFeed(outline opmlOutline) sqlite.Feed {
+	feed := sqlite.Feed{
+		URL:   outline.XMLURL,
+		Type:  sqlite.FeedTypeRSS,
+		Title: firstNonEmpty(outline.Title, outline.Text),
+		Link:  strings.TrimSpace(outline.HTMLURL),
+	}
+
+	switch strings.ToLower(outline.Type) {
+	case "atom":
+		feed.Type = sqlite.FeedTypeAtom
+	case "rdf":
+		feed.Type = sqlite.FeedTypeRDF
+	case "json":
+		feed.Type = sqlite.FeedTypeJSON
+	}
+
+	return feed
+}
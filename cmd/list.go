@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"log"
+	"os"
+	"text/template"
 
 	"feeda/sqlite"
 
@@ -11,23 +14,83 @@ import (
 
 var (
 	unread, setAsRead, onlyURL *bool
+	starred                    *bool
 	limit                      *int64
 	feedID                     *int64
+	listTag                    *string
+	listOutput                 *string
+	listFormat, listTemplate   *string
+	listRender                 *string
 )
 
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List items from feeds",
-	Long:  `List items synced from one or more feeds`,
+	Long: `List items synced from one or more feeds. Items can be printed with a
+Go template given by --format or by --template as the name of a template in
+the [templates] table of ~/.feeda/config.toml. Besides the functions of
+text/template, templates can use:
+
+date "2006-01-02" .PublishedAt   format a time with a layout of the time package
+ago .PublishedAt                 format a time relative to now, such as "3 hours ago"
+truncate 40 .Title               shorten a string to 40 characters
+stripHTML .Desc                  the text of HTML
+color "red" .Title               color a string, one of black, red, green, yellow,
+                                 blue, magenta, cyan, white, bold or dim
+
+Descriptions are rendered by --render as text wrapped to the width of the
+terminal with links listed as footnotes, as Markdown or as the HTML of the
+feed. Text is styled when printed to a terminal unless NO_COLOR is set.
+
+Examples:
+
+# List unread items for dmenu
+feeda list --unread --format '{{.ID}}	{{.FeedTitle}}	{{truncate 60 .Title}}' | dmenu
+
+# Save starred items as Markdown
+feeda list --starred --limit 0 --render markdown > starred.md`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var err error
+		err := checkOutput(*listOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = checkRender(*listRender)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if *listFormat != "" && *listTemplate != "" {
+			log.Fatal("--format and --template can't be used together")
+		}
+
+		var tmpl *template.Template
+		if *listFormat != "" || *listTemplate != "" {
+			if *listOutput != outputText {
+				log.Fatal("--format and --template can't be used with --output")
+			}
+
+			tmpl, err = newItemTemplate(*listFormat, *listTemplate)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
 		filter := sqlite.ItemFilter{}
 
 		if *unread {
 			filter.ReadStatus = sqlite.ItemUnread
 		}
 
+		if *starred {
+			filter.StarStatus = sqlite.ItemStarred
+		}
+
+		if !cmd.Flags().Changed("limit") {
+			*limit = cfg.Limit
+		}
+
 		if *limit > 0 {
 			filter.Limit = *limit
 		}
@@ -36,29 +99,30 @@ var listCmd = &cobra.Command{
 			filter.FeedID = *feedID
 		}
 
+		filter.Tag = *listTag
+
 		items, err := sqlite.ListItems(db, filter)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		var ids []int64
-		for _, item := range items {
-			if *onlyURL {
-				fmt.Printf("%s\n", item.URL)
-			} else {
-				fmt.Printf("%d. %s\n", item.ID, item.Title)
-				fmt.Println(item.URL)
-				fmt.Printf("Published: %s\n", item.PublishedAt.Format("2006-01-02 15:04:05"))
-				if item.ReadAt != nil {
-					fmt.Printf("Read: %s\n", item.ReadAt.Format("2006-01-02 15:04:05"))
-				} else {
-					fmt.Println("Unread")
-				}
-				fmt.Println(item.Desc)
-				fmt.Println("")
+		if tmpl != nil {
+			ids, err = printItemsTemplate(tmpl, items)
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else if *listOutput == outputText {
+			ids = printItems(items, *onlyURL, *listRender)
+		} else {
+			for _, item := range items {
+				ids = append(ids, item.ID)
 			}
 
-			ids = append(ids, item.ID)
+			err = printItemsOutput(*listOutput, items)
+			if err != nil {
+				log.Fatal(err)
+			}
 		}
 
 		if *setAsRead {
@@ -67,12 +131,162 @@ var listCmd = &cobra.Command{
 	},
 }
 
+// printItems prints items with their enclosures and their descriptions in a
+// render format, returns the IDs of the items
+func printItems(items []*sqlite.Item, onlyURL bool, format string) []int64 {
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	var enclosures map[int64][]*sqlite.Enclosure
+	if !onlyURL {
+		var err error
+		enclosures, err = listItemEnclosures(items)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, item := range items {
+		if onlyURL {
+			fmt.Printf("%s\n", item.URL)
+		} else {
+			fmt.Printf("%d. %s\n", item.ID, item.Title)
+			fmt.Println(item.URL)
+			fmt.Printf("Feed: %s\n", item.FeedTitle)
+			if item.Author != "" {
+				fmt.Printf("Author: %s\n", item.Author)
+			}
+			fmt.Printf("Published: %s\n", item.PublishedAt.Format("2006-01-02 15:04:05"))
+			if item.ReadAt != nil {
+				fmt.Printf("Read: %s\n", item.ReadAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Println("Unread")
+			}
+			if item.StarredAt != nil {
+				fmt.Printf("Starred: %s\n", item.StarredAt.Format("2006-01-02 15:04:05"))
+			}
+			for _, enc := range enclosures[item.ID] {
+				fmt.Printf("Enclosure %d: %s\n", enc.ID, formatEnclosure(enc))
+			}
+			fmt.Println(renderDesc(format, item.Body()))
+			fmt.Println("")
+		}
+	}
+
+	return ids
+}
+
+// printItemsTemplate prints each item with a template followed by a newline,
+// returns the IDs of the items
+func printItemsTemplate(tmpl *template.Template, items []*sqlite.Item) ([]int64, error) {
+	var ids []int64
+
+	err := attachEnclosures(items)
+	if err != nil {
+		return nil, err
+	}
+
+	err = attachCategories(items)
+	if err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, item := range items {
+		ids = append(ids, item.ID)
+
+		err = tmpl.Execute(w, item)
+		if err != nil {
+			return nil, err
+		}
+
+		err = w.WriteByte('\n')
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, w.Flush()
+}
+
+// attachEnclosures sets the enclosures of items
+func attachEnclosures(items []*sqlite.Item) error {
+	enclosures, err := listItemEnclosures(items)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Enclosures = []sqlite.Enclosure{}
+		for _, enc := range enclosures[item.ID] {
+			item.Enclosures = append(item.Enclosures, *enc)
+		}
+	}
+
+	return nil
+}
+
+// attachCategories sets the categories of items
+func attachCategories(items []*sqlite.Item) error {
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	categories, err := sqlite.ListItemCategories(db, ids...)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		item.Categories = categories[item.ID]
+		if item.Categories == nil {
+			item.Categories = []string{}
+		}
+	}
+
+	return nil
+}
+
+// listItemEnclosures returns the enclosures of items by the IDs of the items
+func listItemEnclosures(items []*sqlite.Item) (map[int64][]*sqlite.Enclosure, error) {
+	var ids []int64
+	enclosures := make(map[int64][]*sqlite.Enclosure)
+
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+
+	if len(ids) == 0 {
+		return enclosures, nil
+	}
+
+	encs, err := sqlite.ListEnclosures(db, sqlite.EnclosureFilter{ItemIDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, enc := range encs {
+		enclosures[enc.ItemID] = append(enclosures[enc.ItemID], enc)
+	}
+
+	return enclosures, nil
+}
+
 func init() {
 	RootCmd.AddCommand(listCmd)
 
 	unread = listCmd.Flags().BoolP("unread", "u", false, "List only unread items")
 	setAsRead = listCmd.Flags().BoolP("setAsRead", "r", false, "Set the listed items as read")
-	limit = listCmd.Flags().Int64P("limit", "l", 10, "Limit number of items to listed")
+	limit = listCmd.Flags().Int64P("limit", "l", 10, "Limit number of items to listed, 0 for no limit. Defaults to the limit setting")
 	feedID = listCmd.Flags().Int64P("feed", "f", 0, "Feed ID of items to be listed")
 	onlyURL = listCmd.Flags().BoolP("onlyURL", "o", false, "List only the item's URL")
+	starred = listCmd.Flags().BoolP("starred", "s", false, "List only starred items")
+	listTag = listCmd.Flags().StringP("tag", "t", "", "Tag of feeds of items to be listed")
+	listOutput = addOutputFlag(listCmd)
+	listFormat = listCmd.Flags().String("format", "", "Go template to print each item with")
+	listTemplate = listCmd.Flags().String("template", "", "Name of a template in the config file to print each item with")
+	listRender = addRenderFlag(listCmd)
 }
@@ -4,23 +4,43 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"feeda/sqlite"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	listFeedsTag    *string
+	listFeedsOutput *string
+)
+
 // listFeedsCmd represents the listFeeds command
 var listFeedsCmd = &cobra.Command{
 	Use:   "listFeeds",
 	Short: "List all feeds",
 	Long:  `List all feeds that has been added`,
 	Run: func(cmd *cobra.Command, args []string) {
-		feeds, err := sqlite.ListFeeds(db)
+		err := checkOutput(*listFeedsOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{Tag: *listFeedsTag})
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		if *listFeedsOutput != outputText {
+			err = printFeedsOutput(*listFeedsOutput, feeds)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+
 		for _, feed := range feeds {
 			var attrs []string
 
@@ -40,11 +60,38 @@ var listFeedsCmd = &cobra.Command{
 			}
 			attrs = append(attrs, fmt.Sprintf("Unread: %d", unread))
 
-			fmt.Printf("%d. %s (%s)\n", feed.ID, feed.URL, strings.Join(attrs, ", "))
+			if len(feed.Tags) > 0 {
+				attrs = append(attrs, fmt.Sprintf("Tags: %s", strings.Join(feed.Tags, ", ")))
+			}
+
+			if feed.PostInterval > 0 {
+				attrs = append(attrs, fmt.Sprintf("Posts: every %s", formatAge(feed.PostInterval.Round(time.Minute))))
+			}
+
+			if feed.UpdateInterval > 0 {
+				attrs = append(attrs, fmt.Sprintf("Updates: every %s", formatAge(feed.UpdateInterval)))
+			}
+
+			if retention := formatRetention(feed); retention != "" {
+				attrs = append(attrs, fmt.Sprintf("Prune: %s", retention))
+			}
+
+			if feed.ErrorCount > 0 && feed.LastErrorAt != nil {
+				attrs = append(attrs, fmt.Sprintf("Failed: %d times, last at %s: %s", feed.ErrorCount, feed.LastErrorAt.Format("2006-01-02 15:04:05"), feed.LastError))
+			}
+
+			if feed.Title != "" {
+				fmt.Printf("%d. %s - %s (%s)\n", feed.ID, feed.Title, feed.URL, strings.Join(attrs, ", "))
+			} else {
+				fmt.Printf("%d. %s (%s)\n", feed.ID, feed.URL, strings.Join(attrs, ", "))
+			}
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(listFeedsCmd)
+
+	listFeedsTag = listFeedsCmd.Flags().StringP("tag", "t", "", "List only feeds with the tag")
+	listFeedsOutput = addOutputFlag(listFeedsCmd)
 }
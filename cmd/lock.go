@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// lockFile creates a lock file containing the PID of the process and returns
+// a function removing it. Lock files left by processes that are no longer
+// running are replaced.
+func lockFile(p string) (func(), error) {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		pid, _ := lockPID(p)
+		if pid > 0 && processRunning(pid) {
+			return nil, fmt.Errorf("already running with PID %d, remove %s if it isn't", pid, p)
+		}
+
+		err = os.Remove(p)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err = os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(p)
+		return nil, err
+	}
+
+	return func() { os.Remove(p) }, nil
+}
+
+// lockPID returns the PID in a lock file
+func lockPID(p string) (int, error) {
+	content, err := ioutil.ReadFile(p)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(content)))
+}
+
+// processRunning returns true if a process with the PID is running
+func processRunning(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	// Signal 0 only checks that the process exists
+	return proc.Signal(syscall.Signal(0)) == nil
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// openURL opens the URL in the browser given by the BROWSER environment
+// variable or else in the default browser of the system
+func openURL(u string) error {
+	var c *exec.Cmd
+
+	switch {
+	case os.Getenv("BROWSER") != "":
+		c = exec.Command(os.Getenv("BROWSER"), u)
+	case runtime.GOOS == "darwin":
+		c = exec.Command("open", u)
+	case runtime.GOOS == "windows":
+		c = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		c = exec.Command("xdg-open", u)
+	}
+
+	err := c.Start()
+	if err != nil {
+		return err
+	}
+
+	// Reap the process without waiting for the browser to close
+	go c.Wait()
+
+	return nil
+}
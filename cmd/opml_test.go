@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"feeda/sqlite"
+)
+
+func TestFlattenOutlines(t *testing.T) {
+	const doc = `<opml version="2.0"><body>
+	<outline text="Top" xmlUrl=" https://example.com/top.xml "/>
+	<outline text="Tech, News">
+		<outline text="Go" xmlUrl="https://example.com/go.xml"/>
+		<outline title="Databases">
+			<outline text="SQLite" xmlUrl="https://example.com/sqlite.xml"/>
+		</outline>
+	</outline>
+	<outline text="Empty folder"/>
+	<outline text="Feed with children" xmlUrl="https://example.com/parent.xml">
+		<outline text="Child" xmlUrl="https://example.com/child.xml"/>
+	</outline>
+</body></opml>`
+
+	var content opml
+	err := xml.Unmarshal([]byte(doc), &content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url  string
+		tags []string
+	}{
+		{"https://example.com/top.xml", nil},
+		{"https://example.com/go.xml", []string{"Tech  News"}},
+		{"https://example.com/sqlite.xml", []string{"Tech  News", "Databases"}},
+		{"https://example.com/parent.xml", nil},
+		{"https://example.com/child.xml", nil},
+	}
+
+	outlines := flattenOutlines(content.Body.Outlines, nil)
+	if len(outlines) != len(tests) {
+		t.Fatalf("expecting %d outlines, got %d", len(tests), len(outlines))
+	}
+
+	for i, test := range tests {
+		if outlines[i].XMLURL != test.url {
+			t.Fatalf("%d: expecting %q, got %q", i, test.url, outlines[i].XMLURL)
+		}
+		if !reflect.DeepEqual(outlines[i].Tags, test.tags) {
+			t.Fatalf("%d: expecting %q, got %q", i, test.tags, outlines[i].Tags)
+		}
+	}
+}
+
+func TestNewOPMLFeed(t *testing.T) {
+	tests := []struct {
+		outline  opmlOutline
+		expected sqlite.Feed
+	}{
+		{
+			opmlOutline{Text: "Blog", XMLURL: "https://example.com/rss", HTMLURL: " https://example.com/ "},
+			sqlite.Feed{URL: "https://example.com/rss", Type: sqlite.FeedTypeRSS, Title: "Blog", Link: "https://example.com/"},
+		},
+		{
+			opmlOutline{Text: "Text", Title: "Title", Type: "Atom", XMLURL: "https://example.com/atom"},
+			sqlite.Feed{URL: "https://example.com/atom", Type: sqlite.FeedTypeAtom, Title: "Title"},
+		},
+		{
+			opmlOutline{Type: "rdf", XMLURL: "https://example.com/rdf"},
+			sqlite.Feed{URL: "https://example.com/rdf", Type: sqlite.FeedTypeRDF},
+		},
+		{
+			opmlOutline{Type: "json", XMLURL: "https://example.com/feed.json"},
+			sqlite.Feed{URL: "https://example.com/feed.json", Type: sqlite.FeedTypeJSON},
+		},
+		{
+			opmlOutline{Type: "link", XMLURL: "https://example.com/unknown"},
+			sqlite.Feed{URL: "https://example.com/unknown", Type: sqlite.FeedTypeRSS},
+		},
+	}
+
+	for i, test := range tests {
+		actual := newOPMLFeed(test.outline)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestOPMLRoundTrip(t *testing.T) {
+	feeds := []*sqlite.Feed{
+		{URL: "https://example.com/rss", Type: sqlite.FeedTypeRSS, Title: "Blog & News", Link: "https://example.com/"},
+		{URL: "https://example.com/atom", Type: sqlite.FeedTypeAtom, Title: "Atom", Tags: []string{"tech"}},
+		{URL: "https://example.com/rdf", Type: sqlite.FeedTypeRDF, Tags: []string{"tech", "News"}},
+		{URL: "https://example.com/feed.json", Type: sqlite.FeedTypeJSON, Title: "JSON", Tags: []string{"News"}},
+	}
+
+	var b bytes.Buffer
+	err := writeOPML(&b, newOPML(feeds, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(b.String(), xml.Header) {
+		t.Fatalf("expecting XML header, got %q", b.String())
+	}
+
+	var content opml
+	err = xml.Unmarshal(b.Bytes(), &content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content.Head.DateCreated != "Thu, 02 Jan 2020 03:04:05 +0000" {
+		t.Fatalf("unexpected date created %q", content.Head.DateCreated)
+	}
+
+	// Tagged feeds are nested in a folder per tag, the folders are sorted
+	var folders []string
+	for _, outline := range content.Body.Outlines {
+		if outline.XMLURL == "" {
+			folders = append(folders, outline.Text)
+		}
+	}
+	if !reflect.DeepEqual(folders, []string{"News", "tech"}) {
+		t.Fatalf("expecting folders %q, got %q", []string{"News", "tech"}, folders)
+	}
+
+	// Feeds in many folders are imported once with the tags of all folders
+	imported := make(map[string]sqlite.Feed)
+	for _, outline := range flattenOutlines(content.Body.Outlines, nil) {
+		feed := newOPMLFeed(outline)
+		feed.Tags = append(imported[feed.URL].Tags, outline.Tags...)
+		imported[feed.URL] = feed
+	}
+
+	if len(imported) != len(feeds) {
+		t.Fatalf("expecting %d feeds, got %d", len(feeds), len(imported))
+	}
+
+	for i, feed := range feeds {
+		expected := *feed
+		expected.Title = firstNonEmpty(feed.Title, feed.URL)
+		sort.Strings(expected.Tags)
+
+		actual := imported[feed.URL]
+		sort.Strings(actual.Tags)
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%d: expecting %+v, got %+v", i, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats of the output of listing commands
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+type (
+	// feedOutput is a feed with its counts of items, the intervals and the
+	// max age of its retention are in seconds
+	feedOutput struct {
+		*sqlite.Feed
+		UpdateInterval  int64  `json:"update_interval"`
+		PostInterval    int64  `json:"post_interval"`
+		RetentionMaxAge *int64 `json:"retention_max_age"`
+		Total           int64  `json:"total"`
+		Unread          int64  `json:"unread"`
+	}
+)
+
+var (
+	itemColumns = []string{"id", "feed_id", "feed_title", "guid", "url", "title", "author", "published_at", "updated_at", "read_at", "starred_at", "enclosures", "categories", "desc", "content"}
+	feedColumns = []string{"id", "url", "type", "title", "link", "description", "icon", "tags", "created_at", "synced_at", "total", "unread", "etag", "last_modified", "last_error", "last_error_at", "error_count", "update_interval", "post_interval", "new_items_at", "retention_max_age", "retention_keep"}
+	tagColumns  = []string{"id", "name", "feeds", "unread"}
+)
+
+// addOutputFlag adds the --output flag to a listing command
+func addOutputFlag(cmd *cobra.Command) *string {
+	return cmd.Flags().String("output", outputText, "Format of the output: text, json, jsonl, csv or tsv")
+}
+
+// checkOutput returns an error if the format of the output is unknown
+func checkOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputJSONL, outputCSV, outputTSV:
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format %q, use text, json, jsonl, csv or tsv", format)
+}
+
+// printOutput prints records as a JSON array, as one JSON object per line or
+// as rows of CSV or TSV with a header of the columns
+func printOutput(format string, records []interface{}, columns []string, rows [][]string) error {
+	// Descriptions are HTML so escaping it would only make them harder to read
+	e := json.NewEncoder(os.Stdout)
+	e.SetEscapeHTML(false)
+
+	switch format {
+	case outputJSON:
+		if records == nil {
+			records = []interface{}{}
+		}
+
+		e.SetIndent("", "  ")
+		return e.Encode(records)
+	case outputJSONL:
+		for _, record := range records {
+			err := e.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case outputCSV, outputTSV:
+		w := csv.NewWriter(os.Stdout)
+		if format == outputTSV {
+			w.Comma = '\t'
+		}
+
+		err := w.Write(columns)
+		if err != nil {
+			return err
+		}
+
+		err = w.WriteAll(rows)
+		if err != nil {
+			return err
+		}
+
+		return w.Error()
+	}
+
+	return checkOutput(format)
+}
+
+// printItemsOutput prints items with their enclosures and categories in a
+// machine-readable format
+func printItemsOutput(format string, items []*sqlite.Item) error {
+	var records []interface{}
+	var rows [][]string
+
+	err := attachEnclosures(items)
+	if err != nil {
+		return err
+	}
+
+	err = attachCategories(items)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var urls []string
+		for _, enc := range item.Enclosures {
+			urls = append(urls, enc.URL)
+		}
+
+		records = append(records, item)
+		rows = append(rows, []string{
+			strconv.FormatInt(item.ID, 10),
+			strconv.FormatInt(item.FeedID, 10),
+			item.FeedTitle,
+			item.GUID,
+			item.URL,
+			item.Title,
+			item.Author,
+			formatTime(&item.PublishedAt),
+			formatTime(item.UpdatedAt),
+			formatTime(item.ReadAt),
+			formatTime(item.StarredAt),
+			strings.Join(urls, " "),
+			strings.Join(item.Categories, ","),
+			item.Desc,
+			item.Content,
+		})
+	}
+
+	return printOutput(format, records, itemColumns, rows)
+}
+
+// printFeedsOutput prints feeds with their counts of items in a
+// machine-readable format
+func printFeedsOutput(format string, feeds []*sqlite.Feed) error {
+	var records []interface{}
+	var rows [][]string
+
+	for _, feed := range feeds {
+		total, err := sqlite.CountTotalByFeed(db, feed.ID)
+		if err != nil {
+			return err
+		}
+
+		unread, err := sqlite.CountUnreadByFeed(db, feed.ID)
+		if err != nil {
+			return err
+		}
+
+		if feed.Tags == nil {
+			feed.Tags = []string{}
+		}
+
+		record := feedOutput{
+			Feed:           feed,
+			UpdateInterval: int64(feed.UpdateInterval / time.Second),
+			PostInterval:   int64(feed.PostInterval / time.Second),
+			Total:          total,
+			Unread:         unread,
+		}
+		if feed.RetentionMaxAge != nil {
+			seconds := int64(*feed.RetentionMaxAge / time.Second)
+			record.RetentionMaxAge = &seconds
+		}
+
+		records = append(records, record)
+		rows = append(rows, []string{
+			strconv.FormatInt(feed.ID, 10),
+			feed.URL,
+			string(feed.Type),
+			feed.Title,
+			feed.Link,
+			feed.Description,
+			feed.Icon,
+			strings.Join(feed.Tags, ","),
+			formatTime(&feed.CreatedAt),
+			formatTime(feed.SyncedAt),
+			strconv.FormatInt(total, 10),
+			strconv.FormatInt(unread, 10),
+			feed.ETag,
+			feed.LastModified,
+			feed.LastError,
+			formatTime(feed.LastErrorAt),
+			strconv.FormatInt(feed.ErrorCount, 10),
+			strconv.FormatInt(record.UpdateInterval, 10),
+			strconv.FormatInt(record.PostInterval, 10),
+			formatTime(feed.NewItemsAt),
+			formatOptionalInt(record.RetentionMaxAge),
+			formatOptionalInt(feed.RetentionKeep),
+		})
+	}
+
+	return printOutput(format, records, feedColumns, rows)
+}
+
+// printTagsOutput prints tags with their counts in a machine-readable format
+func printTagsOutput(format string, tags []*sqlite.Tag) error {
+	var records []interface{}
+	var rows [][]string
+
+	for _, tag := range tags {
+		records = append(records, tag)
+		rows = append(rows, []string{
+			strconv.FormatInt(tag.ID, 10),
+			tag.Name,
+			strconv.FormatInt(tag.Feeds, 10),
+			strconv.FormatInt(tag.Unread, 10),
+		})
+	}
+
+	return printOutput(format, records, tagColumns, rows)
+}
+
+// formatTime formats a time as RFC3339, nil is formatted as an empty string
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+// formatOptionalInt formats a number, nil is formatted as an empty string
+func formatOptionalInt(n *int64) string {
+	if n == nil {
+		return ""
+	}
+
+	return strconv.FormatInt(*n, 10)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// defaultPager is the pager used when the PAGER environment variable
+	// isn't set
+	defaultPager = "less"
+)
+
+// page prints text through the pager given by the PAGER environment variable
+// when stdout is a terminal, or else prints it directly. Like git, less is
+// told to quit when the text fits on one screen and to keep colors unless
+// LESS is set.
+func page(text string) error {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if _, ok := os.LookupEnv("PAGER"); !ok {
+		pager = []string{defaultPager}
+	}
+
+	if len(pager) == 0 || pager[0] == "cat" || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		_, err := fmt.Fprint(os.Stdout, text)
+		return err
+	}
+
+	c := exec.Command(pager[0], pager[1:]...)
+	c.Stdin = strings.NewReader(text)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		c.Env = append(os.Environ(), "LESS=FRX")
+	}
+
+	err := c.Run()
+	if _, ok := err.(*exec.Error); ok {
+		// The pager isn't installed
+		_, err = fmt.Fprint(os.Stdout, text)
+	}
+
+	return err
+}
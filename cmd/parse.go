@@ -0,0 +1,430 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"feeda/sqlite"
+)
+
+// parseFeed parses the content of a feed and returns the feed with its
+// metadata updated together with its items
+func parseFeed(body io.Reader, feed sqlite.Feed) (sqlite.Feed, []sqlite.Item, error) {
+	switch feed.Type {
+	case sqlite.FeedTypeRSS:
+		return parseRSS(body, feed)
+	case sqlite.FeedTypeRDF:
+		return parseRDF(body, feed)
+	case sqlite.FeedTypeAtom:
+		return parseAtom(body, feed)
+	case sqlite.FeedTypeJSON:
+		return parseJSONFeed(body, feed)
+	}
+
+	return feed, nil, fmt.Errorf("unsupported feed type %s", feed.Type)
+}
+
+func parseRSS(body io.Reader, feed sqlite.Feed) (sqlite.Feed, []sqlite.Item, error) {
+	var err error
+	var content rss2
+	var items []sqlite.Item
+
+	decoded := xml.NewDecoder(body)
+	err = decoded.Decode(&content)
+	if err != nil {
+		return feed, items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
+	}
+
+	feed.Title = strings.TrimSpace(content.Title)
+	feed.Link = firstNonEmpty(content.Links...)
+	feed.Description = strings.TrimSpace(content.Description)
+	feed.Icon = strings.TrimSpace(content.ImageURL)
+	feed.UpdateInterval = updateInterval(content.TTL, content.UpdatePeriod, content.UpdateFrequency)
+
+	// Items with dates in unknown formats are dated by when they are first seen
+	seen := time.Now()
+
+	for _, item := range content.Items {
+		pubDate, err := parseDate(item.PubDate)
+		if err != nil {
+			pubDate = seen
+		}
+
+		if strings.TrimSpace(item.GUID) == "" {
+			item.GUID = item.Link
+		}
+
+		var enclosures []sqlite.Enclosure
+		for _, enc := range item.Enclosures {
+			if strings.TrimSpace(enc.URL) == "" {
+				continue
+			}
+
+			length, _ := strconv.ParseInt(strings.TrimSpace(enc.Length), 10, 64)
+			enclosures = append(enclosures, sqlite.Enclosure{
+				URL:      strings.TrimSpace(enc.URL),
+				MIMEType: strings.TrimSpace(enc.Type),
+				Length:   length,
+				Duration: parseITunesDuration(item.Duration),
+			})
+		}
+
+		items = append(items, sqlite.Item{
+			FeedID:      feed.ID,
+			GUID:        item.GUID,
+			URL:         item.Link,
+			Title:       item.Title,
+			Desc:        item.Description,
+			Author:      firstNonEmpty(item.Author, item.Creator),
+			PublishedAt: pubDate,
+			Enclosures:  enclosures,
+			Categories:  item.Categories,
+		})
+	}
+
+	return feed, items, nil
+}
+
+func parseRDF(body io.Reader, feed sqlite.Feed) (sqlite.Feed, []sqlite.Item, error) {
+	var err error
+	var content rdf
+	var items []sqlite.Item
+
+	decoded := xml.NewDecoder(body)
+	err = decoded.Decode(&content)
+	if err != nil {
+		return feed, items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
+	}
+
+	feed.Title = strings.TrimSpace(content.Channel.Title)
+	feed.Link = strings.TrimSpace(content.Channel.Link)
+	feed.Description = strings.TrimSpace(content.Channel.Description)
+	feed.Icon = strings.TrimSpace(content.ImageURL)
+	feed.UpdateInterval = updateInterval("", content.Channel.UpdatePeriod, content.Channel.UpdateFrequency)
+
+	seen := time.Now()
+
+	for _, item := range content.Items {
+		pubDate, err := parseDate(item.Date)
+		if err != nil {
+			pubDate = seen
+		}
+
+		link := strings.TrimSpace(item.Link)
+		guid := strings.TrimSpace(item.About)
+		if guid == "" {
+			guid = link
+		}
+
+		items = append(items, sqlite.Item{
+			FeedID:      feed.ID,
+			GUID:        guid,
+			URL:         link,
+			Title:       item.Title,
+			Desc:        item.Description,
+			Author:      strings.TrimSpace(item.Creator),
+			PublishedAt: pubDate,
+			Categories:  item.Subjects,
+		})
+	}
+
+	return feed, items, nil
+}
+
+func parseAtom(body io.Reader, feed sqlite.Feed) (sqlite.Feed, []sqlite.Item, error) {
+	var err error
+	var content atom
+	var items []sqlite.Item
+
+	decoded := xml.NewDecoder(body)
+	err = decoded.Decode(&content)
+	if err != nil {
+		return feed, items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
+	}
+
+	feed.Title = strings.TrimSpace(content.Title)
+	feed.Link = atomAlternateLink(content.Links)
+	feed.Description = strings.TrimSpace(content.Subtitle)
+	feed.Icon = firstNonEmpty(content.Icon, content.Logo)
+	// Atom feeds don't give an update interval, it's set by the response
+	feed.UpdateInterval = 0
+
+	seen := time.Now()
+
+	for _, item := range content.Items {
+		pubDate, err := parseDate(item.Updated)
+		if err != nil {
+			pubDate = seen
+		}
+
+		link := atomAlternateLink(item.Links)
+		if strings.TrimSpace(item.ID) == "" {
+			item.ID = link
+		}
+
+		desc := strings.TrimSpace(item.Content)
+		if desc == "" {
+			desc = strings.TrimSpace(item.Summary)
+		}
+
+		var enclosures []sqlite.Enclosure
+		for _, l := range item.Links {
+			if l.Rel != "enclosure" || strings.TrimSpace(l.Href) == "" {
+				continue
+			}
+
+			length, _ := strconv.ParseInt(strings.TrimSpace(l.Length), 10, 64)
+			enclosures = append(enclosures, sqlite.Enclosure{
+				URL:      strings.TrimSpace(l.Href),
+				MIMEType: strings.TrimSpace(l.Type),
+				Length:   length,
+			})
+		}
+
+		items = append(items, sqlite.Item{
+			FeedID:      feed.ID,
+			GUID:        item.ID,
+			URL:         link,
+			Title:       item.Title,
+			Desc:        desc,
+			Author:      atomAuthor(item.Authors, content.Authors),
+			PublishedAt: pubDate,
+			Enclosures:  enclosures,
+			Categories:  atomCategories(item.Categories),
+		})
+	}
+
+	return feed, items, nil
+}
+
+func parseJSONFeed(body io.Reader, feed sqlite.Feed) (sqlite.Feed, []sqlite.Item, error) {
+	var err error
+	var content jsonFeed
+	var items []sqlite.Item
+
+	err = json.NewDecoder(body).Decode(&content)
+	if err != nil {
+		return feed, items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
+	}
+
+	feed.Title = strings.TrimSpace(content.Title)
+	feed.Link = strings.TrimSpace(content.HomePageURL)
+	feed.Description = strings.TrimSpace(content.Description)
+	feed.Icon = firstNonEmpty(content.Icon, content.Favicon)
+	// JSON feeds don't give an update interval, it's set by the response
+	feed.UpdateInterval = 0
+
+	seen := time.Now()
+
+	for _, item := range content.Items {
+		date := item.DatePublished
+		if date == "" {
+			date = item.DateModified
+		}
+
+		pubDate, err := parseDate(date)
+		if err != nil {
+			pubDate = seen
+		}
+
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		guid := strings.TrimSpace(string(item.ID))
+		if guid == "" {
+			guid = link
+		}
+
+		desc := strings.TrimSpace(item.ContentHTML)
+		if desc == "" {
+			desc = strings.TrimSpace(item.ContentText)
+		}
+
+		var enclosures []sqlite.Enclosure
+		for _, attachment := range item.Attachments {
+			if strings.TrimSpace(attachment.URL) == "" {
+				continue
+			}
+
+			enclosures = append(enclosures, sqlite.Enclosure{
+				URL:      strings.TrimSpace(attachment.URL),
+				MIMEType: strings.TrimSpace(attachment.MIMEType),
+				Length:   attachment.SizeInBytes,
+				Duration: int64(attachment.DurationInSeconds),
+			})
+		}
+
+		items = append(items, sqlite.Item{
+			FeedID:      feed.ID,
+			GUID:        guid,
+			URL:         link,
+			Title:       item.Title,
+			Desc:        desc,
+			Author:      jsonFeedAuthorName(item.Author, item.Authors, content.Author, content.Authors),
+			PublishedAt: pubDate,
+			Enclosures:  enclosures,
+			Categories:  item.Tags,
+		})
+	}
+
+	return feed, items, nil
+}
+
+// atomAlternateLink returns the URL of the alternate link, links without a rel
+// attribute are alternate links
+func atomAlternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+
+	return ""
+}
+
+// atomCategories returns the names of categories, labels are preferred over
+// terms
+func atomCategories(categories []atomCategory) []string {
+	var names []string
+	for _, c := range categories {
+		if name := firstNonEmpty(c.Label, c.Term); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// atomAuthor returns the names of the authors of an entry, entries without
+// authors inherit the authors of the feed
+func atomAuthor(entryAuthors, feedAuthors []atomPerson) string {
+	var names []string
+	for _, p := range entryAuthors {
+		names = append(names, p.Name)
+	}
+
+	if author := joinNames(names...); author != "" {
+		return author
+	}
+
+	names = nil
+	for _, p := range feedAuthors {
+		names = append(names, p.Name)
+	}
+
+	return joinNames(names...)
+}
+
+// jsonFeedAuthorName returns the names of the authors of an item, items
+// without authors inherit the authors of the feed
+func jsonFeedAuthorName(itemAuthor jsonFeedAuthor, itemAuthors []jsonFeedAuthor, feedAuthor jsonFeedAuthor, feedAuthors []jsonFeedAuthor) string {
+	var names []string
+	for _, a := range append([]jsonFeedAuthor{itemAuthor}, itemAuthors...) {
+		names = append(names, a.Name)
+	}
+
+	if author := joinNames(names...); author != "" {
+		return author
+	}
+
+	names = nil
+	for _, a := range append([]jsonFeedAuthor{feedAuthor}, feedAuthors...) {
+		names = append(names, a.Name)
+	}
+
+	return joinNames(names...)
+}
+
+// joinNames joins the names that aren't blank with commas
+func joinNames(names ...string) string {
+	var nonEmpty []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			nonEmpty = append(nonEmpty, name)
+		}
+	}
+
+	return strings.Join(nonEmpty, ", ")
+}
+
+// parseITunesDuration parses <itunes:duration> which is either the number of
+// seconds or in the format HH:MM:SS or MM:SS, returns 0 if the duration is
+// missing or invalid
+func parseITunesDuration(s string) int64 {
+	var seconds int64
+
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0
+	}
+
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+
+		seconds = seconds*60 + int64(n)
+	}
+
+	return seconds
+}
+
+// updateInterval returns how often a feed is updated by its <ttl> in minutes
+// or its <sy:updatePeriod> and <sy:updateFrequency>, the longest one is used
+// if both are given. Returns 0 if the interval is missing or invalid.
+func updateInterval(ttl, period, frequency string) time.Duration {
+	var interval time.Duration
+
+	if minutes, err := strconv.ParseInt(strings.TrimSpace(ttl), 10, 64); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+
+	periods := map[string]time.Duration{
+		"hourly":  time.Hour,
+		"daily":   24 * time.Hour,
+		"weekly":  7 * 24 * time.Hour,
+		"monthly": 30 * 24 * time.Hour,
+		"yearly":  365 * 24 * time.Hour,
+	}
+
+	p, ok := periods[strings.ToLower(strings.TrimSpace(period))]
+	if !ok {
+		return interval
+	}
+
+	// Frequency is the number of updates per period and defaults to 1
+	n, err := strconv.ParseInt(strings.TrimSpace(frequency), 10, 64)
+	if err != nil || n < 1 {
+		n = 1
+	}
+
+	if p/time.Duration(n) > interval {
+		interval = p / time.Duration(n)
+	}
+
+	return interval
+}
+
+// firstNonEmpty returns the first value that isn't blank
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
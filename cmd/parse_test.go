@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"feeda/sqlite"
+)
+
+// parseFixture parses a feed in testdata
+func parseFixture(t *testing.T, name string, parse func(io.Reader, sqlite.Feed) (sqlite.Feed, []sqlite.Item, error)) (sqlite.Feed, []sqlite.Item) {
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	feed, items, err := parse(f, sqlite.Feed{ID: 3, URL: "https://example.org/" + name})
+	if err != nil {
+		t.Fatalf("could not parse %s: %s", name, err)
+	}
+
+	return feed, items
+}
+
+// checkItems compares the parsed items with the expected ones by the fields
+// set by the parsers
+func checkItems(t *testing.T, name string, actual, expected []sqlite.Item) {
+	if len(actual) != len(expected) {
+		t.Fatalf("%s: expecting %d items, got %d", name, len(expected), len(actual))
+	}
+
+	for i, item := range actual {
+		e := expected[i]
+		switch {
+		case item.FeedID != e.FeedID:
+			t.Fatalf("%s %d: expecting feed ID %d, got %d", name, i, e.FeedID, item.FeedID)
+		case item.GUID != e.GUID:
+			t.Fatalf("%s %d: expecting GUID %q, got %q", name, i, e.GUID, item.GUID)
+		case item.URL != e.URL:
+			t.Fatalf("%s %d: expecting URL %q, got %q", name, i, e.URL, item.URL)
+		case item.Title != e.Title:
+			t.Fatalf("%s %d: expecting title %q, got %q", name, i, e.Title, item.Title)
+		case item.Desc != e.Desc:
+			t.Fatalf("%s %d: expecting description %q, got %q", name, i, e.Desc, item.Desc)
+		case item.Author != e.Author:
+			t.Fatalf("%s %d: expecting author %q, got %q", name, i, e.Author, item.Author)
+		case !item.PublishedAt.Equal(e.PublishedAt):
+			t.Fatalf("%s %d: expecting published at %s, got %s", name, i, e.PublishedAt, item.PublishedAt.UTC())
+		case !reflect.DeepEqual(item.Categories, e.Categories):
+			t.Fatalf("%s %d: expecting categories %q, got %q", name, i, e.Categories, item.Categories)
+		case !reflect.DeepEqual(item.Enclosures, e.Enclosures):
+			t.Fatalf("%s %d: expecting enclosures %+v, got %+v", name, i, e.Enclosures, item.Enclosures)
+		}
+	}
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		feed  sqlite.Feed
+		items []sqlite.Item
+	}{
+		{
+			"jsonfeed-v1.json",
+			sqlite.Feed{
+				Title:       "Example Blog",
+				Link:        "https://example.org/",
+				Description: "Posts about examples",
+				Icon:        "https://example.org/favicon.ico",
+			},
+			[]sqlite.Item{
+				{
+					FeedID:      3,
+					GUID:        "https://example.org/posts/1",
+					URL:         "https://example.org/posts/1",
+					Title:       "First post",
+					Desc:        "<p>Hello</p>",
+					Author:      "John Smith",
+					PublishedAt: time.Date(2020, 1, 2, 15, 4, 5, 0, time.UTC),
+					Categories:  []string{"go", "feeds"},
+				},
+				{
+					FeedID:      3,
+					GUID:        "2",
+					URL:         "https://example.com/elsewhere",
+					Title:       "Linked post",
+					Desc:        "Plain text",
+					Author:      "Jane Doe",
+					PublishedAt: time.Date(2020, 1, 3, 8, 0, 0, 0, time.UTC),
+					Enclosures: []sqlite.Enclosure{
+						{URL: "https://example.org/episode.mp3", MIMEType: "audio/mpeg", Length: 1024, Duration: 60},
+					},
+				},
+			},
+		},
+		{
+			"jsonfeed-v1.1.json",
+			sqlite.Feed{
+				Title: "Example Podcast",
+				Link:  "https://example.net/",
+				Icon:  "https://example.net/icon.png",
+			},
+			[]sqlite.Item{
+				{
+					FeedID:      3,
+					GUID:        "a",
+					URL:         "https://example.net/a",
+					Title:       "Two authors",
+					Desc:        "<p>A</p>",
+					Author:      "Ann, Bob",
+					PublishedAt: time.Date(2021, 5, 6, 7, 8, 9, 0, time.UTC),
+				},
+				{
+					FeedID:      3,
+					GUID:        "b",
+					URL:         "https://example.net/b",
+					Title:       "Inherited authors",
+					Desc:        "<p>B</p>",
+					Author:      "Jane Doe, John Smith",
+					PublishedAt: time.Date(2021, 5, 7, 7, 8, 9, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	for i, test := range tests {
+		feed, items := parseFixture(t, test.name, parseJSONFeed)
+
+		actual := []string{feed.Title, feed.Link, feed.Description, feed.Icon}
+		expected := []string{test.feed.Title, test.feed.Link, test.feed.Description, test.feed.Icon}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("%d: expecting %q, got %q", i, expected, actual)
+		}
+
+		checkItems(t, test.name, items, test.items)
+	}
+}
+
+func TestParseRDF(t *testing.T) {
+	feed, items := parseFixture(t, "rdf.xml", parseRDF)
+
+	actual := []string{feed.Title, feed.Link, feed.Description, feed.Icon}
+	expected := []string{"Example News", "https://example.org/", "News about examples", "https://example.org/logo.png"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expecting %q, got %q", expected, actual)
+	}
+
+	if feed.UpdateInterval != 12*time.Hour {
+		t.Fatalf("expecting update interval %s, got %s", 12*time.Hour, feed.UpdateInterval)
+	}
+
+	checkItems(t, "rdf.xml", items, []sqlite.Item{
+		{
+			FeedID:      3,
+			GUID:        "urn:example:news:1",
+			URL:         "https://example.org/news/1",
+			Title:       "First news",
+			Desc:        "<p>Hello</p>",
+			Author:      "Jane Doe",
+			PublishedAt: time.Date(2020, 1, 2, 14, 4, 5, 0, time.UTC),
+			Categories:  []string{"go", "feeds"},
+		},
+		{
+			FeedID:      3,
+			GUID:        "https://example.org/news/2",
+			URL:         "https://example.org/news/2",
+			Title:       "Second news",
+			Desc:        "Plain text",
+			PublishedAt: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+	})
+}
+
+func TestParseJSONFeedInvalid(t *testing.T) {
+	_, _, err := parseFeed(strings.NewReader(`{"items": [`), sqlite.Feed{Type: sqlite.FeedTypeJSON})
+	if err == nil {
+		t.Fatal("expecting an error for invalid JSON")
+	}
+}
+
+func TestParseUpdateInterval(t *testing.T) {
+	tests := []struct {
+		feed     sqlite.Feed
+		content  string
+		expected time.Duration
+	}{
+		{sqlite.Feed{Type: sqlite.FeedTypeRSS}, testRSS, 0},
+		{sqlite.Feed{Type: sqlite.FeedTypeRSS}, `<rss version="2.0"><channel><ttl>60</ttl></channel></rss>`, time.Hour},
+		{sqlite.Feed{Type: sqlite.FeedTypeAtom}, testAtom, 0},
+		{sqlite.Feed{Type: sqlite.FeedTypeJSON}, `{"version": "https://jsonfeed.org/version/1.1", "title": "Blog"}`, 0},
+	}
+
+	for i, test := range tests {
+		// The interval of the last sync is replaced by the one of the content
+		test.feed.UpdateInterval = 24 * time.Hour
+
+		feed, _, err := parseFeed(strings.NewReader(test.content), test.feed)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+
+		if feed.UpdateInterval != test.expected {
+			t.Fatalf("%d: expecting %s, got %s", i, test.expected, feed.UpdateInterval)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneOlderThan *string
+	pruneReadOnly  *bool
+	pruneKeep      *int64
+	pruneTag       *string
+)
+
+// pruneCmd deletes old items according to the retention policy
+var pruneCmd = &cobra.Command{
+	Use:   "prune [feed IDs]",
+	Short: "Delete old items",
+	Long: `Deletes items matching all the given conditions, starred items are always
+kept. Conditions that aren't given default to the [retention] table of the
+config file and can be overridden per feed with the retention command.
+Calling this command without any arguments will prune all feeds. --read-only
+has to be combined with --older-than or --keep-per-feed, or their settings,
+as it would otherwise delete every read item, nothing is pruned if the
+retention of a feed overrides both. Pruned items aren't added again by sync
+while they are still in their feeds. Example:
+
+# Delete read items older than 90 days but keep the 20 newest items of each feed
+feeda prune --older-than=90d --read-only --keep-per-feed=20
+
+# Delete all but the 100 newest items of feeds with ID = 1 and ID = 3
+feeda prune --keep-per-feed=100 1 3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var ids []int64
+
+		for _, arg := range args {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		policy, err := prunePolicy(cmd)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		prune(policy, sqlite.FeedFilter{IDs: ids, Tag: *pruneTag})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pruneCmd)
+
+	pruneOlderThan = pruneCmd.Flags().String("older-than", "", "Delete items published longer ago, such as 90d, 2w or 36h")
+	pruneReadOnly = pruneCmd.Flags().Bool("read-only", false, "Delete only read items")
+	pruneKeep = pruneCmd.Flags().Int64("keep-per-feed", 0, "Number of newest items of each feed to keep")
+	pruneTag = pruneCmd.Flags().StringP("tag", "t", "", "Prune only feeds with the tag")
+}
+
+// prunePolicy returns the retention policy given by the flags of the prune
+// command, flags that aren't given default to the retention settings
+func prunePolicy(cmd *cobra.Command) (sqlite.RetentionPolicy, error) {
+	policy := cfg.retentionPolicy()
+
+	if cmd.Flags().Changed("keep-per-feed") {
+		policy.Keep = *pruneKeep
+	}
+
+	if cmd.Flags().Changed("read-only") {
+		policy.ReadOnly = *pruneReadOnly
+	}
+
+	if policy.Keep < 0 {
+		return policy, errors.New("number of items to keep can't be negative")
+	}
+
+	if cmd.Flags().Changed("older-than") {
+		policy.MaxAge = 0
+
+		if *pruneOlderThan != "" {
+			maxAge, err := parseAge(*pruneOlderThan)
+			if err != nil {
+				return policy, err
+			}
+
+			policy.MaxAge = maxAge
+		}
+	}
+
+	return policy, checkRetention(policy)
+}
+
+// checkRetention returns an error if the policy prunes only read items
+// without an age or a number of items to keep, which deletes every read item
+func checkRetention(policy sqlite.RetentionPolicy) error {
+	if policy.ReadOnly && policy.MaxAge == 0 && policy.Keep == 0 {
+		return errors.New("pruning only read items would delete all of them, give --older-than or --keep-per-feed or set older_than or keep_per_feed in the [retention] table of the config file")
+	}
+
+	return nil
+}
+
+// prune deletes items of the feeds matching the filter and prints the number
+// of deleted items per feed
+func prune(policy sqlite.RetentionPolicy, filter sqlite.FeedFilter) {
+	results, err := sqlite.PruneItems(db, policy, filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var total int64
+	for _, r := range results {
+		if r.Pruned > 0 {
+			fmt.Printf("%d. %d items pruned\n", r.FeedID, r.Pruned)
+			total += r.Pruned
+		}
+	}
+
+	fmt.Printf("%d items pruned in total\n", total)
+}
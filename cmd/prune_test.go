@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"feeda/sqlite"
+)
+
+func TestCheckRetention(t *testing.T) {
+	tests := []struct {
+		policy sqlite.RetentionPolicy
+		valid  bool
+	}{
+		{sqlite.RetentionPolicy{}, true},
+		{sqlite.RetentionPolicy{ReadOnly: true}, false},
+		{sqlite.RetentionPolicy{ReadOnly: true, MaxAge: 24 * time.Hour}, true},
+		{sqlite.RetentionPolicy{ReadOnly: true, Keep: 20}, true},
+		{sqlite.RetentionPolicy{Keep: 20}, true},
+	}
+
+	for i, test := range tests {
+		err := checkRetention(test.policy)
+		if (err == nil) != test.valid {
+			t.Fatalf("%d: expecting valid %t, got %v", i, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+type (
+	// readFlags are the flags selecting the items of the read and unread
+	// commands
+	readFlags struct {
+		all       *bool
+		feedID    *int64
+		tag       *string
+		olderThan *string
+	}
+)
+
+var (
+	readSelection, unreadSelection *readFlags
+)
+
+// readCmd sets items as read
+var readCmd = &cobra.Command{
+	Use:   "read [item IDs or ranges]",
+	Short: "Set items as read",
+	Long: `Sets items as read by their IDs or ranges of IDs, or all items with --all.
+--feed, --tag and --older-than narrow down the items. Examples:
+
+# Set items with ID = 4, ID = 7 and ID = 10 to 20 as read
+feeda read 4 7 10-20
+
+# Catch up on a feed by setting its items older than a week as read
+feeda read --all --feed=1 --older-than=7d`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := readSelection.filter(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		n, err := sqlite.SetItemsAsReadNowByFilter(db, filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%d items set as read\n", n)
+	},
+}
+
+// unreadCmd sets items as unread
+var unreadCmd = &cobra.Command{
+	Use:   "unread [item IDs or ranges]",
+	Short: "Set items as unread",
+	Long: `Sets items as unread by their IDs or ranges of IDs, or all items with --all.
+--feed, --tag and --older-than narrow down the items. Example:
+
+# Set items with ID = 10 to 20 as unread
+feeda unread 10-20`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter, err := unreadSelection.filter(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		n, err := sqlite.SetItemsAsUnreadByFilter(db, filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Printf("%d items set as unread\n", n)
+	},
+}
+
+// addReadFlags adds the flags selecting items to the read or unread command
+func addReadFlags(cmd *cobra.Command) *readFlags {
+	return &readFlags{
+		all:       cmd.Flags().Bool("all", false, "Select all items instead of items by IDs"),
+		feedID:    cmd.Flags().Int64P("feed", "f", 0, "Feed ID of items to be selected"),
+		tag:       cmd.Flags().StringP("tag", "t", "", "Tag of feeds of items to be selected"),
+		olderThan: cmd.Flags().String("older-than", "", "Select items published longer ago, such as 7d, 2w or 36h"),
+	}
+}
+
+// filter returns the filter of the items selected by the arguments and flags
+func (f *readFlags) filter(args []string) (sqlite.ItemFilter, error) {
+	var filter sqlite.ItemFilter
+	var err error
+
+	switch {
+	case len(args) > 0 && *f.all:
+		return filter, errors.New("item IDs and --all can't be used together")
+	case len(args) == 0 && !*f.all:
+		return filter, errors.New("missing item IDs, use --all to select all items")
+	}
+
+	filter.IDs, filter.IDRanges, err = parseItemRanges(args)
+	if err != nil {
+		return filter, err
+	}
+
+	filter.FeedID = *f.feedID
+	filter.Tag = *f.tag
+
+	if *f.olderThan != "" {
+		age, err := parseAge(*f.olderThan)
+		if err != nil {
+			return filter, err
+		}
+
+		filter.PublishedBefore = time.Now().Add(-age)
+	}
+
+	return filter, nil
+}
+
+// parseItemRanges parses the IDs and ranges of IDs of items given as
+// arguments, such as 4 and 10-20
+func parseItemRanges(args []string) ([]int64, []sqlite.IDRange, error) {
+	var ids []int64
+	var ranges []sqlite.IDRange
+
+	for _, arg := range args {
+		i := strings.Index(arg, "-")
+		if i <= 0 {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil || id < 1 {
+				return nil, nil, fmt.Errorf("invalid item ID or range %q", arg)
+			}
+
+			ids = append(ids, id)
+			continue
+		}
+
+		from, to := arg[:i], arg[i+1:]
+
+		id, err := strconv.ParseInt(from, 10, 64)
+		if err != nil || id < 1 {
+			return nil, nil, fmt.Errorf("invalid item ID or range %q", arg)
+		}
+
+		last, err := strconv.ParseInt(to, 10, 64)
+		if err != nil || last < id {
+			return nil, nil, fmt.Errorf("invalid item ID or range %q", arg)
+		}
+
+		ranges = append(ranges, sqlite.IDRange{From: id, To: last})
+	}
+
+	return ids, ranges, nil
+}
+
+func init() {
+	RootCmd.AddCommand(readCmd)
+	RootCmd.AddCommand(unreadCmd)
+
+	readSelection = addReadFlags(readCmd)
+	unreadSelection = addReadFlags(unreadCmd)
+}
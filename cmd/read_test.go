@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"feeda/sqlite"
+)
+
+func TestParseItemRanges(t *testing.T) {
+	tests := []struct {
+		args   []string
+		ids    []int64
+		ranges []sqlite.IDRange
+		err    bool
+	}{
+		{nil, nil, nil, false},
+		{[]string{"4", "7"}, []int64{4, 7}, nil, false},
+		{[]string{"10-20", "3"}, []int64{3}, []sqlite.IDRange{{From: 10, To: 20}}, false},
+		{[]string{"5-5"}, nil, []sqlite.IDRange{{From: 5, To: 5}}, false},
+		{[]string{"20-10"}, nil, nil, true},
+		{[]string{"10-"}, nil, nil, true},
+		{[]string{"-10"}, nil, nil, true},
+		{[]string{"a"}, nil, nil, true},
+	}
+
+	for i, test := range tests {
+		ids, ranges, err := parseItemRanges(test.args)
+		if (err != nil) != test.err {
+			t.Fatalf("%d: expecting error to be %t, got %v", i, test.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(ids, test.ids) || !reflect.DeepEqual(ranges, test.ranges) {
+			t.Fatalf("%d: expecting %v %v, got %v %v", i, test.ids, test.ranges, ids, ranges)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"feeda/render"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Formats of the descriptions of items
+const (
+	renderText     = "text"
+	renderHTML     = "html"
+	renderMarkdown = "markdown"
+)
+
+const (
+	// defaultWidth is the width text is wrapped to when the width of the
+	// terminal is unknown
+	defaultWidth = 80
+)
+
+// addRenderFlag adds the --render flag to a command printing items
+func addRenderFlag(cmd *cobra.Command) *string {
+	return cmd.Flags().String("render", renderText, "Format of the descriptions of items: text, html or markdown")
+}
+
+// checkRender returns an error if the format of descriptions is unknown
+func checkRender(format string) error {
+	switch format {
+	case renderText, renderHTML, renderMarkdown:
+		return nil
+	}
+
+	return fmt.Errorf("unknown render format %q, use text, html or markdown", format)
+}
+
+// renderDesc returns the description of an item in a format. Text is wrapped
+// to the width of the terminal and styled when printed to a terminal.
+func renderDesc(format, desc string) string {
+	switch format {
+	case renderHTML:
+		return desc
+	case renderMarkdown:
+		return render.Markdown(desc)
+	}
+
+	return render.Text(desc, render.Options{Width: terminalWidth(), ANSI: stdoutANSI()})
+}
+
+// terminalWidth returns the width of the terminal of stdout, the COLUMNS
+// environment variable or else the default width
+func terminalWidth() int {
+	if w, _, err := terminal.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+
+	return defaultWidth
+}
+
+// stdoutANSI returns whether stdout is a terminal and colors aren't disabled
+// by the NO_COLOR environment variable
+func stdoutANSI() bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	return terminal.IsTerminal(int(os.Stdout.Fd()))
+}
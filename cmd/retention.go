@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	retentionOlderThan *string
+	retentionKeep      *int64
+	retentionClear     *bool
+)
+
+// retentionCmd shows or overrides the retention of a feed
+var retentionCmd = &cobra.Command{
+	Use:   "retention [feed ID]",
+	Short: "Override the retention of a feed",
+	Long: `Overrides the conditions of the prune command for a feed. Calling this command
+without any flags shows the retention of the feed. Example:
+
+# Never prune items of feed with ID = 3 by age but keep its 50 newest items
+feeda retention 3 --older-than=0d --keep=50
+
+# Use the conditions given to the prune command again
+feeda retention 3 --clear`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{id}})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(feeds) == 0 {
+			log.Fatalf("could not find feed with ID %d", id)
+		}
+
+		feed := feeds[0]
+		changed := *retentionClear
+
+		if *retentionClear {
+			feed.RetentionMaxAge = nil
+			feed.RetentionKeep = nil
+		}
+
+		if cmd.Flags().Changed("older-than") {
+			var maxAge time.Duration
+			maxAge, err = parseAge(*retentionOlderThan)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			feed.RetentionMaxAge = &maxAge
+			changed = true
+		}
+
+		if cmd.Flags().Changed("keep") {
+			feed.RetentionKeep = retentionKeep
+			changed = true
+		}
+
+		if changed {
+			err = sqlite.SetFeedRetention(db, feed.ID, feed.RetentionMaxAge, feed.RetentionKeep)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		retention := formatRetention(feed)
+		if retention == "" {
+			retention = "conditions of the prune command"
+		}
+
+		fmt.Printf("%d. Prune: %s\n", feed.ID, retention)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(retentionCmd)
+
+	retentionOlderThan = retentionCmd.Flags().String("older-than", "", "Delete items published longer ago, such as 90d, 2w or 36h. 0d disables it")
+	retentionKeep = retentionCmd.Flags().Int64("keep", 0, "Number of newest items to keep. 0 disables it")
+	retentionClear = retentionCmd.Flags().Bool("clear", false, "Remove the overrides of the feed")
+}
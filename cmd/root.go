@@ -15,12 +15,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	userAgent = "Feeda_feed_aggregator/1.0"
-)
-
 var (
-	dbPath = "~/.feeda/db.sqlite"
+	// dbPath is given by the --db flag
+	dbPath string
 	db     *sql.DB
 )
 
@@ -53,6 +50,18 @@ feeda list -l=50 -u -r -o | xargs open
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	//	Run: func(cmd *cobra.Command, args []string) { },
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Migrations are shown and run by the migrate command itself and
+		// the config doesn't need the DB
+		if cmd == dbMigrateCmd || cmd == configShowCmd {
+			return
+		}
+
+		err := sqlite.EnsureTables(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -65,37 +74,25 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initDB)
+	cobra.OnInitialize(initConfig, initDB)
 
-	RootCmd.Flags().StringVar(&dbPath, "db", "", "Location of DB, defaults to ~/.feeda/db.sqlite")
+	RootCmd.PersistentFlags().StringVar(&dbPath, "db", "", "Location of DB, defaults to the db setting")
 }
 
-// initDB initializes the SQLite DB
+// initDB opens the SQLite DB, the schema is ensured before each command is run
 func initDB() {
-	var err error
-
-	if dbPath == "" {
-		dbPath, err = homedir.Dir()
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		dbPath = path.Join(dbPath, ".feeda")
-		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-			os.Mkdir(dbPath, os.ModePerm)
-		}
-
-		dbPath = path.Join(dbPath, "db.sqlite")
-	}
-
-	db, err = sql.Open("sqlite3", dbPath)
+	p, err := homedir.Expand(cfg.DB)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = sqlite.EnsureTables(db)
+	if _, err := os.Stat(path.Dir(p)); os.IsNotExist(err) {
+		os.MkdirAll(path.Dir(p), os.ModePerm)
+	}
+
+	// Foreign keys are enabled for every connection in the pool
+	db, err = sql.Open("sqlite3", p+"?_foreign_keys=1")
 	if err != nil {
 		log.Fatal(err)
 	}
-
 }
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"time"
+
+	"feeda/sqlite"
+)
+
+const (
+	// maxBackoff is the longest time a failing feed is waited for, unless
+	// the feed is synced less often than that
+	maxBackoff = 24 * time.Hour
+
+	// postIntervalWeight is the weight of the latest interval between new
+	// items in the average of a feed
+	postIntervalWeight = 0.3
+)
+
+// syncInterval returns how often a feed is synced. Feeds are synced as often
+// as new items are found in them on average, but not more often than they say
+// they are updated. Feeds that have stopped posting are synced less often. The
+// interval is bounded by the min and max interval settings, the interval of the
+// feed in the config file overrides it.
+func syncInterval(feed sqlite.Feed) time.Duration {
+	if interval := cfg.feed(feed.URL).Interval.Duration; interval > 0 {
+		return interval
+	}
+
+	interval := cfg.Interval.Duration
+
+	switch {
+	case feed.PostInterval > 0:
+		interval = feed.PostInterval
+
+		if feed.NewItemsAt != nil && feed.SyncedAt != nil {
+			if quiet := feed.SyncedAt.Sub(*feed.NewItemsAt); quiet > interval {
+				interval = quiet
+			}
+		}
+	case feed.UpdateInterval > 0:
+		interval = feed.UpdateInterval
+	}
+
+	if feed.UpdateInterval > interval {
+		interval = feed.UpdateInterval
+	}
+
+	if min := cfg.MinInterval.Duration; min > 0 && interval < min {
+		interval = min
+	}
+
+	if max := cfg.MaxInterval.Duration; max > 0 && interval > max {
+		interval = max
+	}
+
+	return interval
+}
+
+// nextSync returns when a feed is due to be synced. Feeds that failed to sync
+// are retried with exponential backoff and feeds that have never been synced
+// are due at once.
+func nextSync(feed sqlite.Feed) time.Time {
+	interval := syncInterval(feed)
+
+	if feed.ErrorCount > 0 && feed.LastErrorAt != nil {
+		return feed.LastErrorAt.Add(backoff(interval, feed.ErrorCount))
+	}
+
+	if feed.SyncedAt == nil {
+		return time.Time{}
+	}
+
+	return feed.SyncedAt.Add(interval)
+}
+
+// dueFeeds returns the feeds that are due to be synced at the time
+func dueFeeds(feeds []*sqlite.Feed, at time.Time) []*sqlite.Feed {
+	var due []*sqlite.Feed
+
+	for _, feed := range feeds {
+		if !nextSync(*feed).After(at) {
+			due = append(due, feed)
+		}
+	}
+
+	return due
+}
+
+// backoff returns the interval doubled for each consecutive failure, up to
+// maxBackoff or the interval if it is longer
+func backoff(interval time.Duration, failures int64) time.Duration {
+	if interval >= maxBackoff {
+		return interval
+	}
+
+	d := interval
+	for i := int64(0); i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		return maxBackoff
+	}
+
+	return d
+}
+
+// postInterval returns the average time between new items of a feed after n
+// of its items were found to be new. The average of feeds that haven't had new
+// items before is estimated by the publish dates of their items.
+func postInterval(feed sqlite.Feed, items []sqlite.Item, n int64, now time.Time) time.Duration {
+	if n == 0 {
+		return feed.PostInterval
+	}
+
+	if feed.NewItemsAt == nil {
+		return publishInterval(items)
+	}
+
+	latest := now.Sub(*feed.NewItemsAt) / time.Duration(n)
+	if feed.PostInterval == 0 {
+		return latest
+	}
+
+	return time.Duration(postIntervalWeight*float64(latest) + (1-postIntervalWeight)*float64(feed.PostInterval))
+}
+
+// publishInterval returns the average time between the publish dates of the
+// items, 0 if there are fewer than two items
+func publishInterval(items []sqlite.Item) time.Duration {
+	if len(items) < 2 {
+		return 0
+	}
+
+	first, last := items[0].PublishedAt, items[0].PublishedAt
+	for _, item := range items[1:] {
+		if item.PublishedAt.Before(first) {
+			first = item.PublishedAt
+		}
+		if item.PublishedAt.After(last) {
+			last = item.PublishedAt
+		}
+	}
+
+	return last.Sub(first) / time.Duration(len(items)-1)
+}
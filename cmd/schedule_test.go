@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"feeda/sqlite"
+)
+
+func TestUpdateInterval(t *testing.T) {
+	tests := []struct {
+		ttl, period, frequency string
+		expected               time.Duration
+	}{
+		{"", "", "", 0},
+		{"60", "", "", time.Hour},
+		{"invalid", "", "", 0},
+		{"", "daily", "", 24 * time.Hour},
+		{"", "Hourly", "2", 30 * time.Minute},
+		{"", "weekly", "0", 7 * 24 * time.Hour},
+		{"", "sometimes", "1", 0},
+		// The longest interval is used
+		{"120", "hourly", "1", 2 * time.Hour},
+		{"30", "daily", "1", 24 * time.Hour},
+	}
+
+	for i, test := range tests {
+		actual := updateInterval(test.ttl, test.period, test.frequency)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %s, got %s", i, test.expected, actual)
+		}
+	}
+}
+
+func TestCacheInterval(t *testing.T) {
+	date := time.Date(2019, 12, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		headers  map[string]string
+		expected time.Duration
+	}{
+		{map[string]string{}, 0},
+		{map[string]string{"Cache-Control": "public, max-age=600"}, 10 * time.Minute},
+		{map[string]string{"Cache-Control": "no-cache, max-age=600"}, 0},
+		{map[string]string{"Cache-Control": "max-age=invalid"}, 0},
+		{map[string]string{
+			"Date":    date.Format(http.TimeFormat),
+			"Expires": date.Add(time.Hour).Format(http.TimeFormat),
+		}, time.Hour},
+		{map[string]string{
+			"Date":    date.Format(http.TimeFormat),
+			"Expires": date.Add(-time.Hour).Format(http.TimeFormat),
+		}, 0},
+		// max-age takes precedence over Expires
+		{map[string]string{
+			"Cache-Control": "max-age=60",
+			"Date":          date.Format(http.TimeFormat),
+			"Expires":       date.Add(time.Hour).Format(http.TimeFormat),
+		}, time.Minute},
+	}
+
+	for i, test := range tests {
+		resp := &http.Response{Header: make(http.Header)}
+		for k, v := range test.headers {
+			resp.Header.Set(k, v)
+		}
+
+		actual := cacheInterval(resp)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %s, got %s", i, test.expected, actual)
+		}
+	}
+}
+
+func TestNextSync(t *testing.T) {
+	syncedAt := time.Date(2019, 12, 2, 12, 0, 0, 0, time.UTC)
+	failedAt := syncedAt.Add(time.Hour)
+
+	tests := []struct {
+		feed     sqlite.Feed
+		expected time.Time
+	}{
+		{sqlite.Feed{}, time.Time{}},
+		{sqlite.Feed{SyncedAt: &syncedAt}, syncedAt.Add(cfg.Interval.Duration)},
+		{sqlite.Feed{SyncedAt: &syncedAt, UpdateInterval: 2 * time.Hour}, syncedAt.Add(2 * time.Hour)},
+		{sqlite.Feed{SyncedAt: &syncedAt, UpdateInterval: time.Hour, ErrorCount: 1, LastErrorAt: &failedAt}, failedAt.Add(2 * time.Hour)},
+		{sqlite.Feed{SyncedAt: &syncedAt, UpdateInterval: time.Hour, ErrorCount: 3, LastErrorAt: &failedAt}, failedAt.Add(8 * time.Hour)},
+		{sqlite.Feed{SyncedAt: &syncedAt, UpdateInterval: time.Hour, ErrorCount: 10, LastErrorAt: &failedAt}, failedAt.Add(maxBackoff)},
+		// Intervals are bounded by the max interval
+		{sqlite.Feed{SyncedAt: &syncedAt, UpdateInterval: 7 * 24 * time.Hour, ErrorCount: 2, LastErrorAt: &failedAt}, failedAt.Add(cfg.MaxInterval.Duration)},
+		// Feeds are synced as often as new items are found in them
+		{sqlite.Feed{SyncedAt: &syncedAt, PostInterval: 3 * time.Hour, NewItemsAt: &syncedAt}, syncedAt.Add(3 * time.Hour)},
+		{sqlite.Feed{SyncedAt: &syncedAt, PostInterval: time.Minute, NewItemsAt: &syncedAt}, syncedAt.Add(cfg.MinInterval.Duration)},
+		// but not more often than they say they are updated
+		{sqlite.Feed{SyncedAt: &syncedAt, PostInterval: time.Hour, UpdateInterval: 2 * time.Hour, NewItemsAt: &syncedAt}, syncedAt.Add(2 * time.Hour)},
+		// and less often when they have stopped posting
+		{sqlite.Feed{SyncedAt: &failedAt, PostInterval: 30 * time.Minute, NewItemsAt: &syncedAt}, failedAt.Add(time.Hour)},
+	}
+
+	for i, test := range tests {
+		actual := nextSync(test.feed)
+		if !actual.Equal(test.expected) {
+			t.Fatalf("%d: expecting %s, got %s", i, test.expected, actual)
+		}
+	}
+}
+
+func TestPostInterval(t *testing.T) {
+	now := time.Date(2019, 12, 2, 12, 0, 0, 0, time.UTC)
+	newItemsAt := now.Add(-4 * time.Hour)
+	items := []sqlite.Item{
+		{PublishedAt: now.Add(-time.Hour)},
+		{PublishedAt: now.Add(-5 * time.Hour)},
+		{PublishedAt: now.Add(-3 * time.Hour)},
+	}
+
+	tests := []struct {
+		feed     sqlite.Feed
+		n        int64
+		expected time.Duration
+	}{
+		{sqlite.Feed{PostInterval: time.Hour, NewItemsAt: &newItemsAt}, 0, time.Hour},
+		// The first average is estimated by the publish dates
+		{sqlite.Feed{}, 3, 2 * time.Hour},
+		{sqlite.Feed{}, 1, 2 * time.Hour},
+		{sqlite.Feed{NewItemsAt: &newItemsAt}, 2, 2 * time.Hour},
+		{sqlite.Feed{PostInterval: time.Hour, NewItemsAt: &newItemsAt}, 1, time.Hour + 54*time.Minute},
+	}
+
+	for i, test := range tests {
+		actual := postInterval(test.feed, items, test.n, now)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %s, got %s", i, test.expected, actual)
+		}
+	}
+
+	if publishInterval(items[:1]) != 0 {
+		t.Fatalf("expecting no interval for a single item, got %s", publishInterval(items[:1]))
+	}
+}
+
+func TestDueFeeds(t *testing.T) {
+	now := time.Now()
+	recently := now.Add(-time.Minute)
+	longAgo := now.Add(-48 * time.Hour)
+
+	feeds := []*sqlite.Feed{
+		{ID: 1},
+		{ID: 2, SyncedAt: &recently},
+		{ID: 3, SyncedAt: &longAgo},
+	}
+
+	due := dueFeeds(feeds, now)
+	if len(due) != 2 || due[0].ID != 1 || due[1].ID != 3 {
+		t.Fatalf("expecting feeds 1 and 3 to be due, got %+v", due)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"log"
+	"strings"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchUnread  *bool
+	searchLimit   *int64
+	searchFeedID  *int64
+	searchOnlyURL *bool
+	searchOutput  *string
+	searchRender  *string
+)
+
+// searchCmd represents the search command
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search items",
+	Long: `Searches the title, description and author of items, the most relevant items
+are listed first. The query supports the FTS5 syntax of SQLite. Example:
+
+# Items with both words
+feeda search "sqlite release"
+
+# Items with the phrase
+feeda search '"full-text search"'
+
+# Items with words starting with "postgres" but not "mysql"
+feeda search "postgres* NOT mysql"
+
+# Items written by an author
+feeda search "author:pike"`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkOutput(*searchOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = checkRender(*searchRender)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		filter := sqlite.ItemFilter{}
+
+		if *searchUnread {
+			filter.ReadStatus = sqlite.ItemUnread
+		}
+
+		if !cmd.Flags().Changed("limit") {
+			*searchLimit = cfg.Limit
+		}
+
+		if *searchLimit > 0 {
+			filter.Limit = *searchLimit
+		}
+
+		if *searchFeedID > 0 {
+			filter.FeedID = *searchFeedID
+		}
+
+		items, err := sqlite.SearchItems(db, strings.Join(args, " "), filter)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if *searchOutput != outputText {
+			err = printItemsOutput(*searchOutput, items)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+
+		printItems(items, *searchOnlyURL, *searchRender)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(searchCmd)
+
+	searchUnread = searchCmd.Flags().BoolP("unread", "u", false, "Search only unread items")
+	searchLimit = searchCmd.Flags().Int64P("limit", "l", 10, "Limit number of items to listed, 0 for no limit. Defaults to the limit setting")
+	searchFeedID = searchCmd.Flags().Int64P("feed", "f", 0, "Feed ID of items to be searched")
+	searchOnlyURL = searchCmd.Flags().BoolP("onlyURL", "o", false, "List only the item's URL")
+	searchOutput = addOutputFlag(searchCmd)
+	searchRender = addRenderFlag(searchCmd)
+}
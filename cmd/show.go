@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"feeda/render"
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	showMarkRead, showOpen, showRaw *bool
+)
+
+// showCmd prints a single item in full
+var showCmd = &cobra.Command{
+	Use:   "show [item ID]",
+	Short: "Show an item",
+	Long: `Prints an item with all its metadata and its description rendered as text
+wrapped to the width of the terminal, links are listed as footnotes. The item
+is paged by $PAGER, or less when it isn't set, if stdout is a terminal.
+Examples:
+
+# Show the item with ID = 4 and set it as read
+feeda show 4 --mark-read
+
+# Print the HTML of the description
+feeda show 4 --raw
+
+# Open the item in the browser given by $BROWSER
+feeda show 4 --open`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		items, err := sqlite.ListItems(db, sqlite.ItemFilter{IDs: []int64{id}})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(items) == 0 {
+			log.Fatalf("item %d not found", id)
+		}
+
+		item := items[0]
+
+		if *showOpen {
+			err = openURL(item.URL)
+			if err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			err = attachEnclosures(items)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			err = attachCategories(items)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var b bytes.Buffer
+			writeItem(&b, item, *showRaw, render.Options{Width: terminalWidth(), ANSI: stdoutANSI()})
+
+			err = page(b.String())
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if *showMarkRead {
+			err = sqlite.SetItemsAsReadNow(db, item.ID)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// writeItem writes an item with its metadata followed by its article or else
+// its description, which is rendered as text unless raw
+func writeItem(w io.Writer, item *sqlite.Item, raw bool, opts render.Options) {
+	const layout = "2006-01-02 15:04:05"
+
+	fmt.Fprintf(w, "%d. %s\n", item.ID, strings.TrimSpace(item.Title))
+	fmt.Fprintln(w, item.URL)
+	fmt.Fprintf(w, "Feed: %s\n", item.FeedTitle)
+	if item.Author != "" {
+		fmt.Fprintf(w, "Author: %s\n", item.Author)
+	}
+	if len(item.Categories) > 0 {
+		fmt.Fprintf(w, "Categories: %s\n", strings.Join(item.Categories, ", "))
+	}
+	fmt.Fprintf(w, "Published: %s\n", item.PublishedAt.Format(layout))
+	if item.UpdatedAt != nil {
+		fmt.Fprintf(w, "Updated: %s\n", item.UpdatedAt.Format(layout))
+	}
+	if item.ReadAt != nil {
+		fmt.Fprintf(w, "Read: %s\n", item.ReadAt.Format(layout))
+	} else {
+		fmt.Fprintln(w, "Unread")
+	}
+	if item.StarredAt != nil {
+		fmt.Fprintf(w, "Starred: %s\n", item.StarredAt.Format(layout))
+	}
+	for _, enc := range item.Enclosures {
+		fmt.Fprintf(w, "Enclosure %d: %s\n", enc.ID, formatEnclosure(&enc))
+	}
+
+	desc := item.Body()
+	if !raw {
+		desc = render.Text(desc, opts)
+	}
+
+	if desc != "" {
+		fmt.Fprintf(w, "\n%s\n", desc)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(showCmd)
+
+	showMarkRead = showCmd.Flags().BoolP("mark-read", "r", false, "Set the item as read")
+	showOpen = showCmd.Flags().BoolP("open", "o", false, "Open the item in the browser instead of printing it")
+	showRaw = showCmd.Flags().Bool("raw", false, "Print the stored HTML of the article or description")
+}
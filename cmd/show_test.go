@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"feeda/render"
+	"feeda/sqlite"
+)
+
+func TestWriteItem(t *testing.T) {
+	published := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := &sqlite.Item{
+		ID:          4,
+		FeedTitle:   "Blog",
+		URL:         "https://example.com/post",
+		Title:       " Post ",
+		Desc:        `<p>Read <a href="https://example.com/more">more</a></p>`,
+		Author:      "Jane",
+		PublishedAt: published,
+		ReadAt:      &published,
+		Categories:  []string{"Go", "SQL"},
+		Enclosures:  []sqlite.Enclosure{{ID: 1, URL: "https://example.com/a.mp3"}},
+	}
+
+	tests := []struct {
+		raw      bool
+		expected string
+	}{
+		{false, `4. Post
+https://example.com/post
+Feed: Blog
+Author: Jane
+Categories: Go, SQL
+Published: 2020-01-02 03:04:05
+Read: 2020-01-02 03:04:05
+Enclosure 1: https://example.com/a.mp3
+
+Read more[1]
+
+[1] https://example.com/more
+`},
+		{true, `4. Post
+https://example.com/post
+Feed: Blog
+Author: Jane
+Categories: Go, SQL
+Published: 2020-01-02 03:04:05
+Read: 2020-01-02 03:04:05
+Enclosure 1: https://example.com/a.mp3
+
+<p>Read <a href="https://example.com/more">more</a></p>
+`},
+	}
+
+	for i, test := range tests {
+		var b bytes.Buffer
+		writeItem(&b, item, test.raw, render.Options{Width: 80})
+
+		if b.String() != test.expected {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, b.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"log"
+	"strconv"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+// starCmd stars one or more items
+var starCmd = &cobra.Command{
+	Use:   "star [item IDs]",
+	Short: "Star items",
+	Long: `Stars one or more items to keep them, starred items are not deleted unless
+forced. Example:
+
+# Star items with ID = 4 and ID = 7
+feeda star 4 7`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := sqlite.SetItemsAsStarredNow(db, parseItemIDs(args)...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// unstarCmd unstars one or more items
+var unstarCmd = &cobra.Command{
+	Use:   "unstar [item IDs]",
+	Short: "Unstar items",
+	Long:  `Unstars one or more items`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := sqlite.SetItemsAsUnstarred(db, parseItemIDs(args)...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(starCmd)
+	RootCmd.AddCommand(unstarCmd)
+}
+
+// parseItemIDs parses the IDs of items given as arguments
+func parseItemIDs(args []string) []int64 {
+	var ids []int64
+
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
@@ -1,9 +1,7 @@
 package cmd
 
 import (
-	"encoding/xml"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -16,6 +14,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	syncTag   *string
+	syncPrune *bool
+	syncDue   *bool
+)
+
 // syncCmd fetches one or multiple feeds and persists their items
 // to DB
 var syncCmd = &cobra.Command{
@@ -29,10 +33,29 @@ be synced. Example:
 # Sync only feeds with ID = 1 and ID = 3
 sync 1 3
 
+# Sync all feeds tagged with "news"
+sync --tag=news
+
+# Sync only feeds that are due by how often new items are found in them, such
+# as from cron every minute
+sync --due
+
+# Sync all feeds and prune their items by the retention settings of the config
+# file and of the feeds
+sync --prune
+
 # Sync all feeds
 sync`,
 	Run: func(cmd *cobra.Command, args []string) {
-		var ids, syncedAtIds []int64
+		var ids []int64
+		var failed []string
+
+		if *syncPrune {
+			err := checkRetention(cfg.retentionPolicy())
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
 
 		for _, arg := range args {
 			id, err := strconv.ParseInt(arg, 10, 64)
@@ -43,148 +66,182 @@ sync`,
 			ids = append(ids, id)
 		}
 
-		feeds, err := sqlite.ListFeeds(db, ids...)
+		feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: ids, Tag: *syncTag})
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		c := &http.Client{
-			Timeout: 10 * time.Second,
-		}
-		var wg sync.WaitGroup
-		for _, feed := range feeds {
-			wg.Add(1)
-			syncedAtIds = append(syncedAtIds, feed.ID)
-
-			go func(feed sqlite.Feed) {
-				req, err := http.NewRequest(http.MethodGet, feed.URL, nil)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				req.Header.Set("User-Agent", userAgent)
-				resp, err := c.Do(req)
-				if err != nil {
-					log.Fatalf("could not fetch URL %s: %v", feed.URL, err)
-				}
-				defer resp.Body.Close()
-
-				var items []sqlite.Item
-				if feed.Type == sqlite.FeedTypeRSS {
-					items, err = createItemsFromRSS(resp.Body, feed)
-					if err != nil {
-						log.Fatal(err)
-					}
-				} else if feed.Type == sqlite.FeedTypeAtom {
-					items, err = createItemsFromAtom(resp.Body, feed)
-					if err != nil {
-						log.Fatal(err)
-					}
-				}
-
-				var inserted int64
-				if len(items) > 0 {
-					inserted, err = sqlite.CreateIgnoreItems(db, items...)
-					if err != nil {
-						log.Fatal(err)
-					}
-
-				}
-
-				fmt.Printf("%d. %d items added\n", feed.ID, inserted)
-
-				wg.Done()
-			}(*feed)
+		if *syncDue {
+			feeds = dueFeeds(feeds, time.Now())
 		}
 
-		wg.Wait()
-
-		err = sqlite.SetFeedsSyncedAtNow(db, syncedAtIds...)
+		syncedAtIds, err := syncFeeds(newHTTPClient(), feeds, func(r syncResult) {
+			switch {
+			case r.err != nil:
+				log.Printf("%d. could not sync %s: %s", r.feed.ID, r.feed.URL, r.err)
+				failed = append(failed, strconv.FormatInt(r.feed.ID, 10))
+			case r.modified:
+				fmt.Printf("%d. %d items added or updated\n", r.feed.ID, r.upserted)
+			default:
+				fmt.Printf("%d. not modified\n", r.feed.ID)
+			}
+		})
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		if *syncPrune && len(syncedAtIds) > 0 {
+			prune(cfg.retentionPolicy(), sqlite.FeedFilter{IDs: syncedAtIds})
+		}
+
+		if len(failed) > 0 {
+			log.Fatalf("%d of %d feeds failed to sync: %s", len(failed), len(feeds), strings.Join(failed, ", "))
+		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(syncCmd)
+
+	syncTag = syncCmd.Flags().StringP("tag", "t", "", "Sync only feeds with the tag")
+	syncPrune = syncCmd.Flags().Bool("prune", false, "Prune items of the synced feeds by the retention settings")
+	syncDue = syncCmd.Flags().Bool("due", false, "Sync only feeds that are due, bounded by the min_interval and max_interval settings")
 }
 
-func createItemsFromRSS(body io.Reader, feed sqlite.Feed) ([]sqlite.Item, error) {
-	var err error
-	var content rss2
-	var items []sqlite.Item
+// syncResult is the outcome of syncing a feed
+type syncResult struct {
+	feed     sqlite.Feed
+	upserted int64
+	modified bool
+	err      error
+}
 
-	decoded := xml.NewDecoder(body)
-	err = decoded.Decode(&content)
-	if err != nil {
-		return items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
-	}
+// syncFeeds syncs feeds concurrently and records their errors and when they
+// were synced, returns the IDs of the synced feeds. The results of the feeds
+// are passed to done one at a time as they finish.
+func syncFeeds(c *http.Client, feeds []*sqlite.Feed, done func(r syncResult)) ([]int64, error) {
+	var syncedAtIds []int64
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for _, feed := range feeds {
+		wg.Add(1)
+
+		go func(feed sqlite.Feed) {
+			defer wg.Done()
+
+			upserted, modified, err := syncFeed(c, feed)
+
+			mu.Lock()
+			defer mu.Unlock()
 
-	for _, item := range content.Items {
-		pubDate, err := time.Parse(time.RFC1123Z, item.PubDate)
-		if err != nil {
-			// Try to parse as "Mon, 2 Jan 2006 15:04:05 -0700" (without leading zero on the day of month)
-			pubDate, err = time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", item.PubDate)
 			if err != nil {
-				return items, fmt.Errorf("could not parse PubDate for URL %s: %s", feed.URL, err)
+				recordErr := sqlite.SetFeedError(db, feed.ID, err.Error())
+				if recordErr != nil {
+					log.Printf("%d. could not record error: %s", feed.ID, recordErr)
+				}
+			} else {
+				syncedAtIds = append(syncedAtIds, feed.ID)
 			}
 
-		}
+			done(syncResult{feed: feed, upserted: upserted, modified: modified, err: err})
+		}(*feed)
+	}
 
-		if strings.TrimSpace(item.GUID) == "" {
-			item.GUID = item.Link
-		}
+	wg.Wait()
 
-		items = append(items, sqlite.Item{
-			FeedID:      feed.ID,
-			GUID:        item.GUID,
-			URL:         item.Link,
-			Title:       item.Title,
-			Desc:        item.Description,
-			PublishedAt: pubDate,
-		})
+	if len(syncedAtIds) == 0 {
+		return nil, nil
 	}
 
-	return items, nil
+	return syncedAtIds, sqlite.SetFeedsSyncedAtNow(db, syncedAtIds...)
 }
 
-func createItemsFromAtom(body io.Reader, feed sqlite.Feed) ([]sqlite.Item, error) {
-	var err error
-	var content atom
-	var items []sqlite.Item
+// syncFeed fetches a feed and persists its new and changed items, returns the
+// number of inserted or updated items and whether the feed was modified since
+// the last sync
+func syncFeed(c *http.Client, feed sqlite.Feed) (int64, bool, error) {
+	req, err := newRequest(http.MethodGet, feed.URL, nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	resp, err := feedHTTPClient(c, feed.URL).Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not fetch URL %s: %v", feed.URL, err)
+	}
+	defer resp.Body.Close()
+
+	// Feed hasn't changed since the last sync
+	if resp.StatusCode == http.StatusNotModified {
+		return 0, false, nil
+	}
 
-	decoded := xml.NewDecoder(body)
-	err = decoded.Decode(&content)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	parsed, items, err := parseFeed(resp.Body, feed)
 	if err != nil {
-		return items, fmt.Errorf("could not read data for URL %s: %s", feed.URL, err)
+		return 0, false, err
 	}
 
-	for _, item := range content.Items {
-		pubDate, err := time.Parse(time.RFC3339, item.Updated)
+	var upserted, added int64
+	if len(items) > 0 {
+		newItems, err := sqlite.ListNewItems(db, items...)
 		if err != nil {
-			return items, fmt.Errorf("could not parse Updated for URL %s: %s", feed.URL, err)
+			return 0, false, err
 		}
 
-		if strings.TrimSpace(item.ID) == "" {
-			item.ID = item.Link.Href
+		added = int64(len(newItems))
+
+		upserted, err = sqlite.UpsertItems(db, items...)
+		if err != nil {
+			return 0, false, err
 		}
 
-		desc := strings.TrimSpace(item.Content)
-		if desc == "" {
-			desc = strings.TrimSpace(item.Summary)
+		// Pruned items are only remembered while they are in the feed
+		_, err = sqlite.ExpirePrunedItems(db, feed.ID, items...)
+		if err != nil {
+			return 0, false, err
 		}
 
-		items = append(items, sqlite.Item{
-			FeedID:      feed.ID,
-			GUID:        item.ID,
-			URL:         item.Link.Href,
-			Title:       item.Title,
-			Desc:        desc,
-			PublishedAt: pubDate,
-		})
+		err = syncArticles(c, feed, newItems)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	if added > 0 {
+		now := time.Now()
+
+		err = sqlite.SetFeedPostStats(db, feed.ID, postInterval(feed, items, added, now), now)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	// Feeds are synced as often as the longest of the intervals they give
+	if interval := cacheInterval(resp); interval > parsed.UpdateInterval {
+		parsed.UpdateInterval = interval
+	}
+
+	err = sqlite.SetFeedMeta(db, parsed)
+	if err != nil {
+		return 0, false, err
+	}
+
+	err = sqlite.SetFeedCacheHeaders(db, feed.ID, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"))
+	if err != nil {
+		return 0, false, err
 	}
 
-	return items, nil
+	return upserted, true, nil
 }
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"feeda/sqlite"
+)
+
+const testRSSItem = `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title>` +
+	`<item><guid>1</guid><link>https://example.com/1</link><title>First</title></item></channel></rss>`
+
+// openTestDB replaces the DB of the commands with an empty one in a temporary
+// directory, the returned func closes it and restores the previous DB
+func openTestDB(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "feeda")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := db
+	db, err = sql.Open("sqlite3", filepath.Join(dir, "feeds.sqlite")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.EnsureTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return func() {
+		db.Close()
+		db = prev
+		os.RemoveAll(dir)
+	}
+}
+
+// testFeed adds a feed with the URL and returns it as listed from the DB
+func testFeed(t *testing.T, u string) sqlite.Feed {
+	err := sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: u, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return reloadFeed(t, u)
+}
+
+// reloadFeed returns the feed with the URL as listed from the DB
+func reloadFeed(t *testing.T, u string) sqlite.Feed {
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, feed := range feeds {
+		if feed.URL == u {
+			return *feed
+		}
+	}
+
+	t.Fatalf("could not find feed %s", u)
+	return sqlite.Feed{}
+}
+
+func TestSyncFeedNotModified(t *testing.T) {
+	defer openTestDB(t)()
+
+	const etag, lastModified = `"v1"`, "Thu, 02 Jan 2020 03:04:05 GMT"
+
+	var ifNoneMatch, ifModifiedSince string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch = r.Header.Get("If-None-Match")
+		ifModifiedSince = r.Header.Get("If-Modified-Since")
+
+		if ifNoneMatch == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(testRSSItem))
+	}))
+	defer srv.Close()
+
+	feed := testFeed(t, srv.URL)
+
+	upserted, modified, err := syncFeed(srv.Client(), feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upserted != 1 || !modified {
+		t.Fatalf("expecting 1 item upserted and modified feed, got %d and %t", upserted, modified)
+	}
+	if ifNoneMatch != "" || ifModifiedSince != "" {
+		t.Fatalf("expecting no conditional headers on the first sync, got %q and %q", ifNoneMatch, ifModifiedSince)
+	}
+
+	feed = reloadFeed(t, srv.URL)
+	if feed.ETag != etag || feed.LastModified != lastModified || feed.Title != "Blog" {
+		t.Fatalf("expecting ETag %q, Last-Modified %q and title %q, got %q, %q and %q", etag, lastModified, "Blog", feed.ETag, feed.LastModified, feed.Title)
+	}
+
+	// The second sync sends the cache headers and gets no content
+	upserted, modified, err = syncFeed(srv.Client(), feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upserted != 0 || modified {
+		t.Fatalf("expecting no items upserted and unmodified feed, got %d and %t", upserted, modified)
+	}
+	if ifNoneMatch != etag || ifModifiedSince != lastModified {
+		t.Fatalf("expecting conditional headers %q and %q, got %q and %q", etag, lastModified, ifNoneMatch, ifModifiedSince)
+	}
+
+	feed = reloadFeed(t, srv.URL)
+	if feed.ETag != etag || feed.LastModified != lastModified || feed.Title != "Blog" {
+		t.Fatalf("expecting ETag %q, Last-Modified %q and title %q to be kept, got %q, %q and %q", etag, lastModified, "Blog", feed.ETag, feed.LastModified, feed.Title)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expecting 1 item, got %d", len(items))
+	}
+}
+
+func TestSyncFeedsError(t *testing.T) {
+	defer openTestDB(t)()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte(testRSSItem))
+	}))
+	defer srv.Close()
+
+	good := testFeed(t, srv.URL+"/good")
+	broken := testFeed(t, srv.URL+"/broken")
+
+	results := make(map[int64]syncResult)
+	synced, err := syncFeeds(srv.Client(), []*sqlite.Feed{&good, &broken}, func(r syncResult) {
+		results[r.feed.ID] = r
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(synced) != 1 || synced[0] != good.ID {
+		t.Fatalf("expecting only feed %d to be synced, got %v", good.ID, synced)
+	}
+	if results[good.ID].err != nil || results[good.ID].upserted != 1 {
+		t.Fatalf("expecting 1 item upserted without error, got %+v", results[good.ID])
+	}
+	if results[broken.ID].err == nil {
+		t.Fatal("expecting an error for the broken feed")
+	}
+
+	good = reloadFeed(t, srv.URL+"/good")
+	if good.SyncedAt == nil || good.LastError != "" || good.ErrorCount != 0 {
+		t.Fatalf("expecting good feed to be synced without errors, got %v, %q and %d", good.SyncedAt, good.LastError, good.ErrorCount)
+	}
+
+	broken = reloadFeed(t, srv.URL+"/broken")
+	if broken.SyncedAt != nil || broken.LastError == "" || broken.LastErrorAt == nil || broken.ErrorCount != 1 {
+		t.Fatalf("expecting broken feed to have an error, got %v, %q, %v and %d", broken.SyncedAt, broken.LastError, broken.LastErrorAt, broken.ErrorCount)
+	}
+
+	// Failures are counted until the feed is synced again
+	_, err = syncFeeds(srv.Client(), []*sqlite.Feed{&broken}, func(r syncResult) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broken = reloadFeed(t, srv.URL+"/broken")
+	if broken.ErrorCount != 2 {
+		t.Fatalf("expecting 2 consecutive errors, got %d", broken.ErrorCount)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"log"
+	"strconv"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+// tagCmd is the parent of the commands changing the tags of feeds
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Tag feeds",
+	Long:  `Adds and removes tags of feeds, a feed can have many tags`,
+}
+
+// tagAddCmd tags a feed
+var tagAddCmd = &cobra.Command{
+	Use:   "add [feed ID] [tags]",
+	Short: "Add tags to a feed",
+	Long: `Adds one or more tags to a feed. Tags are case-insensitive. Example:
+
+# Tag feed with ID = 3 with "news" and "tech"
+feeda tag add 3 news tech`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := tagFeedID(args[0])
+
+		for _, tag := range args[1:] {
+			err := sqlite.TagFeeds(db, tag, id)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+// tagRmCmd removes tags from a feed
+var tagRmCmd = &cobra.Command{
+	Use:   "rm [feed ID] [tags]",
+	Short: "Remove tags from a feed",
+	Long: `Removes one or more tags from a feed. Example:
+
+# Remove the tag "tech" from feed with ID = 3
+feeda tag rm 3 tech`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := tagFeedID(args[0])
+
+		for _, tag := range args[1:] {
+			err := sqlite.UntagFeeds(db, tag, id)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRmCmd)
+	RootCmd.AddCommand(tagCmd)
+}
+
+// tagFeedID parses the ID of the feed to tag and checks that the feed exists
+func tagFeedID(arg string) int64 {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{id}})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(feeds) == 0 {
+		log.Fatalf("could not find feed with ID %d", id)
+	}
+
+	return id
+}
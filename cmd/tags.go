@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"feeda/sqlite"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagsOutput *string
+)
+
+// tagsCmd represents the tags command
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "List all tags",
+	Long:  `List all tags of feeds with their number of feeds and unread items`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := checkOutput(*tagsOutput)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		tags, err := sqlite.ListTags(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if *tagsOutput != outputText {
+			err = printTagsOutput(*tagsOutput, tags)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			return
+		}
+
+		for _, tag := range tags {
+			fmt.Printf("%s (Feeds: %d, Unread: %d)\n", tag.Name, tag.Feeds, tag.Unread)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tagsCmd)
+
+	tagsOutput = addOutputFlag(tagsCmd)
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// templateEscapes are unescaped in templates given as flags as shells
+	// don't unescape them in quoted strings
+	templateEscapes = strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\\`, `\`)
+
+	// colors are the ANSI escape codes of the colors of the color function
+	colors = map[string]string{
+		"black":   "30",
+		"red":     "31",
+		"green":   "32",
+		"yellow":  "33",
+		"blue":    "34",
+		"magenta": "35",
+		"cyan":    "36",
+		"white":   "37",
+		"bold":    "1",
+		"dim":     "2",
+	}
+
+	// blockTags separate the words of their text from the surrounding text
+	blockTags = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true,
+		"br": true, "dd": true, "div": true, "dl": true, "dt": true,
+		"figcaption": true, "figure": true, "footer": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"header": true, "hr": true, "li": true, "ol": true, "p": true,
+		"pre": true, "section": true, "table": true, "td": true, "th": true,
+		"tr": true, "ul": true,
+	}
+
+	templateFuncs = template.FuncMap{
+		"date":      templateDate,
+		"ago":       templateAgo,
+		"truncate":  templateTruncate,
+		"stripHTML": stripHTML,
+		"color":     templateColor,
+	}
+)
+
+// newItemTemplate parses a template of the list command, a name refers to a
+// template in the config file
+func newItemTemplate(format, name string) (*template.Template, error) {
+	if name != "" {
+		var ok bool
+		format, ok = cfg.Templates[name]
+		if !ok {
+			return nil, fmt.Errorf("could not find template %q in the config file", name)
+		}
+	} else {
+		format = templateEscapes.Replace(format)
+	}
+
+	return template.New("item").Funcs(templateFuncs).Parse(format)
+}
+
+// templateTime returns the time of a time.Time or *time.Time, nil and zero
+// times are returned as false
+func templateTime(t interface{}) (time.Time, bool) {
+	switch v := t.(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case *time.Time:
+		if v != nil {
+			return *v, !v.IsZero()
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// templateDate formats a time with a layout of the time package, such as
+// {{date "2006-01-02" .PublishedAt}}
+func templateDate(layout string, t interface{}) string {
+	v, ok := templateTime(t)
+	if !ok {
+		return ""
+	}
+
+	return v.Local().Format(layout)
+}
+
+// templateAgo formats a time relative to now, such as {{ago .PublishedAt}}
+// giving "3 hours ago"
+func templateAgo(t interface{}) string {
+	v, ok := templateTime(t)
+	if !ok {
+		return ""
+	}
+
+	d := time.Since(v)
+	suffix := "ago"
+	if d < 0 {
+		d = -d
+		suffix = "from now"
+	}
+
+	var n int64
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int64(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int64(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int64(d/(24*time.Hour)), "day"
+	case d < 365*24*time.Hour:
+		n, unit = int64(d/(30*24*time.Hour)), "month"
+	default:
+		n, unit = int64(d/(365*24*time.Hour)), "year"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s %s", n, unit, suffix)
+}
+
+// templateTruncate shortens a string to a number of characters including an
+// ellipsis, such as {{truncate 40 .Title}}
+func templateTruncate(length int, s string) string {
+	runes := []rune(s)
+	if length <= 0 || len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length-1]) + "…"
+}
+
+// templateColor colors a string with ANSI escape codes, such as
+// {{color "red" .Title}}. Colors are disabled by the NO_COLOR environment
+// variable.
+func templateColor(name, s string) (string, error) {
+	code, ok := colors[name]
+	if !ok {
+		return "", fmt.Errorf("unknown color %q", name)
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return s, nil
+	}
+
+	return fmt.Sprintf("\x1b[%sm%s\x1b[0m", code, s), nil
+}
+
+// stripHTML returns the text of HTML with whitespace collapsed, such as
+// {{stripHTML .Desc}}
+func stripHTML(s string) string {
+	var b bytes.Buffer
+	var skip bool
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			if !skip {
+				b.Write(z.Text())
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+
+			if tag == "script" || tag == "style" {
+				skip = tt == html.StartTagToken
+			}
+
+			if blockTags[tag] {
+				b.WriteByte(' ')
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		actual   string
+		expected string
+	}{
+		{templateTruncate(5, "Hello world"), "Hell…"},
+		{templateTruncate(5, "Hello"), "Hello"},
+		{templateTruncate(3, "Åäöüß"), "Åä…"},
+		{stripHTML("<p>Hello <b>w</b>orld</p><p>a &amp; b</p>"), "Hello world a & b"},
+		{stripHTML("<style>p {}</style>Text<br>more<script>alert(1)</script>"), "Text more"},
+		{templateAgo(time.Now().Add(-30 * time.Second)), "just now"},
+		{templateAgo(time.Now().Add(-3*time.Hour - time.Minute)), "3 hours ago"},
+		{templateAgo(time.Now().Add(-25 * time.Hour)), "1 day ago"},
+		{templateAgo((*time.Time)(nil)), ""},
+		{templateDate("2006-01-02", time.Date(2019, 12, 2, 12, 0, 0, 0, time.Local)), "2019-12-02"},
+		{templateDate("2006-01-02", time.Time{}), ""},
+	}
+
+	for i, test := range tests {
+		if test.actual != test.expected {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, test.actual)
+		}
+	}
+}
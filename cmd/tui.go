@@ -0,0 +1,536 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"feeda/sqlite"
+
+	"github.com/gdamore/tcell"
+	"github.com/spf13/cobra"
+)
+
+// Panes of the TUI
+const (
+	paneSources = iota
+	paneItems
+	paneContent
+)
+
+const (
+	tuiHelp = "q quit  tab pane  j/k move  enter read  r read/unread  s star  o open  u unread only  / search  S sync"
+)
+
+type (
+	// tuiSource is an entry of the feeds pane, such as a tag or a feed
+	tuiSource struct {
+		label  string
+		unread int64
+		// filter lists the items of the source
+		filter sqlite.ItemFilter
+		// feeds are synced when syncing the source
+		feeds sqlite.FeedFilter
+	}
+
+	// tuiSyncEvent reports the progress of a sync to the event loop
+	tuiSyncEvent struct {
+		tcell.EventTime
+		done, failed, total int
+		finished            bool
+		err                 error
+	}
+
+	// tui is the state of the terminal UI
+	tui struct {
+		screen  tcell.Screen
+		sources []tuiSource
+		items   []*sqlite.Item
+		focus   int
+
+		// Selected rows and the first visible rows of the panes
+		source, sourceTop int
+		item, itemTop     int
+		contentTop        int
+
+		unreadOnly bool
+		query      string
+		prompting  bool
+		input      []rune
+		syncing    bool
+		status     string
+	}
+)
+
+var (
+	tuiUnread *bool
+)
+
+// tuiCmd reads items in a full-screen terminal UI
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Read items in a terminal UI",
+	Long: `Opens a full-screen reader with panes of feeds and tags, items and the content
+of the selected item. Keys:
+
+tab, shift+tab, h, l  Switch pane
+j, k, up, down        Move in the pane
+g, G, home, end       Move to the top or bottom of the pane
+space, pgup, pgdn     Move by a page
+enter                 Read the selected item and set it as read
+r                     Set the selected item as read or unread
+s                     Star or unstar the selected item
+o                     Open the selected item in the browser and set it as read
+u                     Show only unread items or all items
+/                     Search items, esc clears the search
+S                     Sync the feeds of the selected entry of the feeds pane
+?                     Show the keys
+q, ctrl+c             Quit`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := tcell.NewScreen()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		err = s.Init()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// Logs would garble the screen
+		log.SetOutput(ioutil.Discard)
+
+		t := &tui{screen: s, unreadOnly: *tuiUnread}
+		err = t.run()
+
+		s.Fini()
+		log.SetOutput(os.Stderr)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tuiCmd)
+
+	tuiUnread = tuiCmd.Flags().BoolP("unread", "u", false, "Show only unread items")
+}
+
+// run loads the feeds and items and handles events until the TUI is quit
+func (t *tui) run() error {
+	err := t.loadSources()
+	if err == nil {
+		err = t.loadItems()
+	}
+	if err != nil {
+		return err
+	}
+
+	for {
+		t.draw()
+
+		switch ev := t.screen.PollEvent().(type) {
+		case *tcell.EventResize:
+			t.screen.Sync()
+		case *tcell.EventKey:
+			if t.handleKey(ev) {
+				return nil
+			}
+		case *tuiSyncEvent:
+			t.handleSync(ev)
+		}
+	}
+}
+
+// loadSources lists all items, starred items, the tags and the feeds with
+// their numbers of unread items
+func (t *tui) loadSources() error {
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		return err
+	}
+
+	tags, err := sqlite.ListTags(db)
+	if err != nil {
+		return err
+	}
+
+	sources := []tuiSource{
+		{label: "All"},
+		{label: "Starred", filter: sqlite.ItemFilter{StarStatus: sqlite.ItemStarred}},
+	}
+
+	for _, tag := range tags {
+		sources = append(sources, tuiSource{
+			label:  "#" + tag.Name,
+			unread: tag.Unread,
+			filter: sqlite.ItemFilter{Tag: tag.Name},
+			feeds:  sqlite.FeedFilter{Tag: tag.Name},
+		})
+	}
+
+	for _, feed := range feeds {
+		unread, err := sqlite.CountUnreadByFeed(db, feed.ID)
+		if err != nil {
+			return err
+		}
+
+		sources[0].unread += unread
+		sources = append(sources, tuiSource{
+			label:  firstNonEmpty(feed.Title, feed.URL),
+			unread: unread,
+			filter: sqlite.ItemFilter{FeedID: feed.ID},
+			feeds:  sqlite.FeedFilter{IDs: []int64{feed.ID}},
+		})
+	}
+
+	t.sources = sources
+	if t.source >= len(sources) {
+		t.source = len(sources) - 1
+	}
+
+	return nil
+}
+
+// loadItems lists the items of the selected source, or the items matching the
+// search, keeping the selected item if it is still listed
+func (t *tui) loadItems() error {
+	var items []*sqlite.Item
+	var err error
+	var selected int64
+
+	if item := t.selected(); item != nil {
+		selected = item.ID
+	}
+
+	filter := t.sources[t.source].filter
+	if t.unreadOnly {
+		filter.ReadStatus = sqlite.ItemUnread
+	}
+
+	if t.query != "" {
+		items, err = sqlite.SearchItems(db, t.query, filter)
+	} else {
+		items, err = sqlite.ListItems(db, filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.items = items
+	t.item = 0
+	t.contentTop = 0
+
+	for i, item := range items {
+		if item.ID == selected {
+			t.item = i
+		}
+	}
+
+	return nil
+}
+
+// selected returns the selected item, nil if there are no items
+func (t *tui) selected() *sqlite.Item {
+	if t.item < 0 || t.item >= len(t.items) {
+		return nil
+	}
+
+	return t.items[t.item]
+}
+
+// handleKey handles a key press, returns true if the TUI should quit
+func (t *tui) handleKey(ev *tcell.EventKey) bool {
+	if t.prompting {
+		t.handlePromptKey(ev)
+		return false
+	}
+
+	if !t.syncing {
+		t.status = ""
+	}
+
+	_, h := t.screen.Size()
+	page := h / 2
+
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		return true
+	case tcell.KeyTab, tcell.KeyRight:
+		t.focus = (t.focus + 1) % 3
+	case tcell.KeyBacktab, tcell.KeyLeft:
+		t.focus = (t.focus + 2) % 3
+	case tcell.KeyDown:
+		t.move(1)
+	case tcell.KeyUp:
+		t.move(-1)
+	case tcell.KeyPgDn:
+		t.move(page)
+	case tcell.KeyPgUp:
+		t.move(-page)
+	case tcell.KeyHome:
+		t.move(-1 << 30)
+	case tcell.KeyEnd:
+		t.move(1 << 30)
+	case tcell.KeyEnter:
+		t.enter()
+	case tcell.KeyEscape:
+		if t.query != "" {
+			t.query = ""
+			t.reloadItems()
+		}
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case 'l':
+			t.focus = (t.focus + 1) % 3
+		case 'h':
+			t.focus = (t.focus + 2) % 3
+		case 'j':
+			t.move(1)
+		case 'k':
+			t.move(-1)
+		case ' ':
+			t.move(page)
+		case 'g':
+			t.move(-1 << 30)
+		case 'G':
+			t.move(1 << 30)
+		case 'r':
+			t.toggleRead()
+		case 's':
+			t.toggleStar()
+		case 'o':
+			t.open()
+		case 'u':
+			t.unreadOnly = !t.unreadOnly
+			t.reloadItems()
+		case '/':
+			t.prompting = true
+			t.input = []rune(t.query)
+		case 'S':
+			t.sync()
+		case '?':
+			t.status = tuiHelp
+		}
+	}
+
+	return false
+}
+
+// handlePromptKey edits the search query
+func (t *tui) handlePromptKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		t.prompting = false
+		t.query = string(t.input)
+
+		err := t.loadItems()
+		if err == sqlite.ErrSearchUnavailable {
+			t.query = ""
+			t.status = "Search is unavailable, feeda must be built with the sqlite_fts5 tag"
+			t.reloadItems()
+		} else if err != nil {
+			t.status = err.Error()
+		}
+	case tcell.KeyEscape, tcell.KeyCtrlC:
+		t.prompting = false
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case tcell.KeyRune:
+		t.input = append(t.input, ev.Rune())
+	}
+}
+
+// move moves the selection of the focused pane by n rows, or scrolls the
+// content
+func (t *tui) move(n int) {
+	switch t.focus {
+	case paneSources:
+		source := clamp(t.source+n, 0, len(t.sources)-1)
+		if source != t.source {
+			t.source = source
+			t.reloadItems()
+		}
+	case paneItems:
+		item := clamp(t.item+n, 0, len(t.items)-1)
+		if item != t.item {
+			t.item = item
+			t.contentTop = 0
+		}
+	case paneContent:
+		// Scrolling past the end is clamped when drawing
+		t.contentTop = clamp(t.contentTop+n, 0, 1<<30)
+	}
+}
+
+// enter moves the focus to the items of the selected source or reads the
+// selected item
+func (t *tui) enter() {
+	switch t.focus {
+	case paneSources:
+		t.focus = paneItems
+	case paneItems:
+		t.focus = paneContent
+
+		if item := t.selected(); item != nil && item.ReadAt == nil {
+			t.toggleRead()
+		}
+	}
+}
+
+// toggleRead sets the selected item as read or unread
+func (t *tui) toggleRead() {
+	item := t.selected()
+	if item == nil {
+		return
+	}
+
+	var err error
+	if item.ReadAt == nil {
+		err = sqlite.SetItemsAsReadNow(db, item.ID)
+		now := time.Now()
+		item.ReadAt = &now
+	} else {
+		err = sqlite.SetItemsAsUnread(db, item.ID)
+		item.ReadAt = nil
+	}
+
+	if err == nil {
+		err = t.loadSources()
+	}
+	if err != nil {
+		t.status = err.Error()
+	}
+}
+
+// toggleStar stars or unstars the selected item
+func (t *tui) toggleStar() {
+	item := t.selected()
+	if item == nil {
+		return
+	}
+
+	var err error
+	if item.StarredAt == nil {
+		err = sqlite.SetItemsAsStarredNow(db, item.ID)
+		now := time.Now()
+		item.StarredAt = &now
+	} else {
+		err = sqlite.SetItemsAsUnstarred(db, item.ID)
+		item.StarredAt = nil
+	}
+
+	if err != nil {
+		t.status = err.Error()
+	}
+}
+
+// open opens the selected item in the browser and sets it as read
+func (t *tui) open() {
+	item := t.selected()
+	if item == nil {
+		return
+	}
+
+	err := openURL(item.URL)
+	if err != nil {
+		t.status = fmt.Sprintf("could not open %s: %s", item.URL, err)
+		return
+	}
+
+	if item.ReadAt == nil {
+		t.toggleRead()
+	}
+}
+
+// reloadItems loads the items and shows any error in the status bar
+func (t *tui) reloadItems() {
+	err := t.loadItems()
+	if err != nil {
+		t.status = err.Error()
+	}
+}
+
+// sync syncs the feeds of the selected source in the background, the progress
+// is posted to the event loop
+func (t *tui) sync() {
+	if t.syncing {
+		return
+	}
+
+	feeds, err := sqlite.ListFeeds(db, t.sources[t.source].feeds)
+	if err != nil {
+		t.status = err.Error()
+		return
+	}
+
+	t.syncing = true
+	t.status = fmt.Sprintf("Syncing %d feeds", len(feeds))
+
+	go func() {
+		var done, failed int
+
+		_, err := syncFeeds(newHTTPClient(), feeds, func(r syncResult) {
+			done++
+			if r.err != nil {
+				failed++
+			}
+
+			t.screen.PostEventWait(newSyncEvent(done, failed, len(feeds), false, nil))
+		})
+
+		t.screen.PostEventWait(newSyncEvent(done, failed, len(feeds), true, err))
+	}()
+}
+
+// handleSync shows the progress of a sync and reloads the feeds and items when
+// it has finished
+func (t *tui) handleSync(ev *tuiSyncEvent) {
+	if !ev.finished {
+		t.status = fmt.Sprintf("Syncing %s %d/%d feeds, %d failed", progressBar(ev.done, ev.total, 20), ev.done, ev.total, ev.failed)
+		return
+	}
+
+	t.syncing = false
+	t.status = fmt.Sprintf("Synced %d feeds, %d failed", ev.done-ev.failed, ev.failed)
+
+	err := ev.err
+	if err == nil {
+		err = t.loadSources()
+	}
+	if err == nil {
+		err = t.loadItems()
+	}
+	if err != nil {
+		t.status = err.Error()
+	}
+}
+
+// newSyncEvent returns an event of the progress of a sync
+func newSyncEvent(done, failed, total int, finished bool, err error) *tuiSyncEvent {
+	ev := &tuiSyncEvent{done: done, failed: failed, total: total, finished: finished, err: err}
+	ev.SetEventNow()
+
+	return ev
+}
+
+// clamp returns n bounded by min and max, min wins if max is below it
+func clamp(n, min, max int) int {
+	if n > max {
+		n = max
+	}
+	if n < min {
+		n = min
+	}
+
+	return n
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"feeda/render"
+
+	"github.com/gdamore/tcell"
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// tuiLine is a line of the content pane
+type tuiLine struct {
+	text  string
+	style tcell.Style
+}
+
+// draw draws the panes and the status bar. The feeds pane is on the left, the
+// items are above the content on the right.
+func (t *tui) draw() {
+	s := t.screen
+	s.Clear()
+
+	w, h := s.Size()
+	left := clamp(w/4, 16, 32)
+	x := left + 1
+	right := w - x
+	itemsHeight := clamp((h-3)/3, 3, h)
+	contentY := itemsHeight + 2
+	contentHeight := h - 1 - contentY
+
+	for y := 0; y < h-1; y++ {
+		s.SetContent(left, y, tcell.RuneVLine, nil, tcell.StyleDefault)
+	}
+
+	// Feeds and tags
+	t.drawHeader(0, 0, left, "Feeds", paneSources)
+	t.sourceTop = scrollTop(t.source, t.sourceTop, h-2)
+	for i := 0; i < h-2 && t.sourceTop+i < len(t.sources); i++ {
+		n := t.sourceTop + i
+		source := t.sources[n]
+
+		label := source.label
+		if source.unread > 0 {
+			count := fmt.Sprintf(" %d", source.unread)
+			label = runewidth.Truncate(label, left-len(count), "…")
+			label += strings.Repeat(" ", left-len(count)-runewidth.StringWidth(label)) + count
+		}
+
+		t.drawRow(0, 1+i, left, label, t.rowStyle(paneSources, n == t.source, source.unread > 0))
+	}
+
+	// Items
+	title := fmt.Sprintf("Items of %s (%d)", t.sources[t.source].label, len(t.items))
+	if t.query != "" {
+		title += fmt.Sprintf(" matching %q", t.query)
+	}
+	if t.unreadOnly {
+		title += ", unread only"
+	}
+
+	t.drawHeader(x, 0, right, title, paneItems)
+	t.itemTop = scrollTop(t.item, t.itemTop, itemsHeight)
+	for i := 0; i < itemsHeight && t.itemTop+i < len(t.items); i++ {
+		n := t.itemTop + i
+		item := t.items[n]
+
+		flags := []rune("   ")
+		if item.ReadAt == nil {
+			flags[0] = 'N'
+		}
+		if item.StarredAt != nil {
+			flags[1] = '*'
+		}
+
+		row := fmt.Sprintf("%s%s  %s", string(flags), item.PublishedAt.Format("2006-01-02"), strings.TrimSpace(item.Title))
+		if t.sources[t.source].filter.FeedID == 0 {
+			row += " - " + item.FeedTitle
+		}
+
+		t.drawRow(x, 1+i, right, row, t.rowStyle(paneItems, n == t.item, item.ReadAt == nil))
+	}
+
+	// Content of the selected item
+	t.drawHeader(x, contentY-1, right, "Content", paneContent)
+	lines := t.contentLines(right - 1)
+	t.contentTop = clamp(t.contentTop, 0, len(lines)-contentHeight)
+	for i := 0; i < contentHeight && t.contentTop+i < len(lines); i++ {
+		line := lines[t.contentTop+i]
+		t.drawRow(x+1, contentY+i, right-1, line.text, line.style)
+	}
+
+	// Status bar or search prompt
+	s.HideCursor()
+	if t.prompting {
+		prompt := "/" + string(t.input)
+		t.drawRow(0, h-1, w, prompt, tcell.StyleDefault)
+		s.ShowCursor(runewidth.StringWidth(prompt), h-1)
+	} else {
+		status := t.status
+		if status == "" {
+			status = "? keys"
+		}
+
+		t.drawRow(0, h-1, w, status, tcell.StyleDefault.Reverse(true))
+	}
+
+	s.Show()
+}
+
+// drawHeader draws the title of a pane, the title of the focused pane is
+// highlighted
+func (t *tui) drawHeader(x, y, width int, title string, pane int) {
+	style := tcell.StyleDefault.Bold(true)
+	if t.focus == pane {
+		style = style.Reverse(true)
+	}
+
+	t.drawRow(x, y, width, " "+title, style)
+}
+
+// rowStyle returns the style of a row of a list, unread rows are bold
+func (t *tui) rowStyle(pane int, selected, unread bool) tcell.Style {
+	style := tcell.StyleDefault.Bold(unread)
+
+	switch {
+	case selected && t.focus == pane:
+		style = style.Reverse(true)
+	case selected:
+		style = style.Underline(true)
+	}
+
+	return style
+}
+
+// drawRow draws text truncated to the width and pads it with spaces so the
+// style covers the whole row
+func (t *tui) drawRow(x, y, width int, text string, style tcell.Style) {
+	for _, r := range text {
+		rw := runewidth.RuneWidth(r)
+		if r == '\t' {
+			r, rw = ' ', 1
+		}
+		if rw > width {
+			break
+		}
+
+		t.screen.SetContent(x, y, r, nil, style)
+		x += rw
+		width -= rw
+	}
+
+	for ; width > 0; width-- {
+		t.screen.SetContent(x, y, ' ', nil, style)
+		x++
+	}
+}
+
+// contentLines returns the lines of the selected item wrapped to the width
+func (t *tui) contentLines(width int) []tuiLine {
+	var lines []tuiLine
+
+	item := t.selected()
+	if item == nil {
+		return []tuiLine{{text: "No items", style: tcell.StyleDefault}}
+	}
+
+	add := func(text string, style tcell.Style) {
+		for _, line := range wrapText(text, width) {
+			lines = append(lines, tuiLine{text: line, style: style})
+		}
+	}
+
+	add(strings.TrimSpace(item.Title), tcell.StyleDefault.Bold(true))
+	add(joinNames(item.FeedTitle, item.Author, item.PublishedAt.Format("2006-01-02 15:04")), tcell.StyleDefault.Dim(true))
+	add(item.URL, tcell.StyleDefault.Underline(true))
+	add("", tcell.StyleDefault)
+
+	// The description is already wrapped, keeping the indents of lists and code
+	for _, line := range strings.Split(render.Text(item.Body(), render.Options{Width: width}), "\n") {
+		lines = append(lines, tuiLine{text: line, style: tcell.StyleDefault})
+	}
+
+	return lines
+}
+
+// scrollTop returns the first visible row of a list so the selected row is
+// visible
+func scrollTop(selected, top, height int) int {
+	if selected < top {
+		return selected
+	}
+
+	if selected >= top+height {
+		return selected - height + 1
+	}
+
+	return top
+}
+
+// progressBar returns a bar of the width showing done out of total
+func progressBar(done, total, width int) string {
+	filled := width
+	if total > 0 {
+		filled = done * width / total
+	}
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+// wrapText wraps the lines of text to the width, words longer than the width
+// are broken
+func wrapText(text string, width int) []string {
+	var lines []string
+
+	if width < 1 {
+		width = 1
+	}
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line string
+
+		for _, word := range strings.Fields(paragraph) {
+			for runewidth.StringWidth(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+
+				head := runewidth.Truncate(word, width, "")
+				if head == "" {
+					// The first rune is wider than the width
+					head = string([]rune(word)[:1])
+				}
+
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+
+			switch {
+			case word == "":
+			case line == "":
+				line = word
+			case runewidth.StringWidth(line)+1+runewidth.StringWidth(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
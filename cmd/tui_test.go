@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		{"", 10, []string{""}},
+		{"Hello world", 20, []string{"Hello world"}},
+		{"Hello  big world", 9, []string{"Hello big", "world"}},
+		{"First\n\nSecond", 10, []string{"First", "", "Second"}},
+		{"Supercalifragilistic word", 8, []string{"Supercal", "ifragili", "stic", "word"}},
+		{"日本語のテキスト", 6, []string{"日本語", "のテキ", "スト"}},
+	}
+
+	for i, test := range tests {
+		actual := wrapText(test.text, test.width)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestScrollTop(t *testing.T) {
+	tests := []struct {
+		selected, top, height, expected int
+	}{
+		{0, 0, 10, 0},
+		{5, 0, 10, 0},
+		{10, 0, 10, 1},
+		{3, 5, 10, 3},
+		{20, 5, 10, 11},
+	}
+
+	for i, test := range tests {
+		actual := scrollTop(test.selected, test.top, test.height)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %d, got %d", i, test.expected, actual)
+		}
+	}
+}
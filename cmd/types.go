@@ -1,36 +1,190 @@
 package cmd
 
-import "encoding/xml"
+import (
+	"encoding/json"
+	"encoding/xml"
+)
 
 type (
 	rss2 struct {
-		XMLName xml.Name   `xml:"rss"`
-		Items   []rss2Item `xml:"channel>item"`
+		XMLName     xml.Name `xml:"rss"`
+		Title       string   `xml:"channel>title"`
+		Description string   `xml:"channel>description"`
+		ImageURL    string   `xml:"channel>image>url"`
+		// TTL is the number of minutes the feed can be cached
+		TTL             string `xml:"channel>ttl"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ channel>updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ channel>updateFrequency"`
+		// Links of other namespaces such as <atom:link> are also matched
+		Links []string   `xml:"channel>link"`
+		Items []rss2Item `xml:"channel>item"`
 	}
 
 	rss2Item struct {
-		Title       string `xml:"title"`
-		Link        string `xml:"link"`
-		Description string `xml:"description"`
-		GUID        string `xml:"guid"`
-		PubDate     string `xml:"pubDate"`
+		Title       string          `xml:"title"`
+		Link        string          `xml:"link"`
+		Description string          `xml:"description"`
+		GUID        string          `xml:"guid"`
+		PubDate     string          `xml:"pubDate"`
+		Author      string          `xml:"author"`
+		Creator     string          `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Categories  []string        `xml:"category"`
+		Enclosures  []rss2Enclosure `xml:"enclosure"`
+		Duration    string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	}
+
+	rss2Enclosure struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	}
+
+	// rdf is a RSS 1.0 feed where items are siblings of the channel
+	rdf struct {
+		XMLName  xml.Name   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# RDF"`
+		Channel  rdfChannel `xml:"channel"`
+		ImageURL string     `xml:"image>url"`
+		Items    []rdfItem  `xml:"item"`
+	}
+
+	rdfChannel struct {
+		Title           string `xml:"title"`
+		Link            string `xml:"link"`
+		Description     string `xml:"description"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	}
+
+	rdfItem struct {
+		About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+		Title       string   `xml:"title"`
+		Link        string   `xml:"link"`
+		Description string   `xml:"description"`
+		Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
 	}
 
 	atom struct {
-		XMLName xml.Name   `xml:"feed"`
-		Items   []atomItem `xml:"entry"`
+		XMLName  xml.Name     `xml:"feed"`
+		Title    string       `xml:"title"`
+		Subtitle string       `xml:"subtitle"`
+		Links    []atomLink   `xml:"link"`
+		Icon     string       `xml:"icon"`
+		Logo     string       `xml:"logo"`
+		Authors  []atomPerson `xml:"author"`
+		Items    []atomItem   `xml:"entry"`
 	}
 
 	atomLink struct {
-		Href string `xml:"href,attr"`
+		Rel    string `xml:"rel,attr"`
+		Href   string `xml:"href,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
 	}
 
 	atomItem struct {
-		Title   string   `xml:"title"`
-		Link    atomLink `xml:"link"`
-		ID      string   `xml:"id"`
-		Content string   `xml:"content"`
-		Summary string   `xml:"summary"`
-		Updated string   `xml:"updated"`
+		Title      string         `xml:"title"`
+		Links      []atomLink     `xml:"link"`
+		ID         string         `xml:"id"`
+		Content    string         `xml:"content"`
+		Summary    string         `xml:"summary"`
+		Updated    string         `xml:"updated"`
+		Authors    []atomPerson   `xml:"author"`
+		Categories []atomCategory `xml:"category"`
+	}
+
+	atomPerson struct {
+		Name string `xml:"name"`
+	}
+
+	// atomCategory is a category of an entry, the label is the
+	// human-readable name of the term
+	atomCategory struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	}
+
+	jsonFeed struct {
+		Version     string           `json:"version"`
+		Title       string           `json:"title"`
+		HomePageURL string           `json:"home_page_url"`
+		Description string           `json:"description"`
+		Icon        string           `json:"icon"`
+		Favicon     string           `json:"favicon"`
+		Author      jsonFeedAuthor   `json:"author"`
+		Authors     []jsonFeedAuthor `json:"authors"`
+		Items       []jsonFeedItem   `json:"items"`
+	}
+
+	jsonFeedItem struct {
+		ID            jsonFeedID `json:"id"`
+		URL           string     `json:"url"`
+		ExternalURL   string     `json:"external_url"`
+		Title         string     `json:"title"`
+		ContentHTML   string     `json:"content_html"`
+		ContentText   string     `json:"content_text"`
+		DatePublished string     `json:"date_published"`
+		DateModified  string     `json:"date_modified"`
+		Tags          []string   `json:"tags"`
+		// Author is replaced by Authors in JSON Feed 1.1
+		Author      jsonFeedAuthor   `json:"author"`
+		Authors     []jsonFeedAuthor `json:"authors"`
+		Attachments []struct {
+			URL               string  `json:"url"`
+			MIMEType          string  `json:"mime_type"`
+			SizeInBytes       int64   `json:"size_in_bytes"`
+			DurationInSeconds float64 `json:"duration_in_seconds"`
+		} `json:"attachments"`
 	}
+
+	jsonFeedAuthor struct {
+		Name string `json:"name"`
+	}
+
+	opml struct {
+		XMLName xml.Name `xml:"opml"`
+		Version string   `xml:"version,attr"`
+		Head    opmlHead `xml:"head"`
+		Body    opmlBody `xml:"body"`
+	}
+
+	opmlHead struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	}
+
+	opmlBody struct {
+		Outlines []opmlOutline `xml:"outline"`
+	}
+
+	opmlOutline struct {
+		Text     string        `xml:"text,attr"`
+		Title    string        `xml:"title,attr,omitempty"`
+		Type     string        `xml:"type,attr,omitempty"`
+		XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+		HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+		Outlines []opmlOutline `xml:"outline"`
+		// Tags are the texts of the folders the outline is nested in
+		Tags []string `xml:"-"`
+	}
+
+	// jsonFeedID is a string in the spec but some publishers use numbers
+	jsonFeedID string
 )
+
+func (id *jsonFeedID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*id = jsonFeedID(n.String())
+
+	return nil
+}
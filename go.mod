@@ -3,7 +3,12 @@ module feeda
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/gdamore/tcell v1.4.0
+	github.com/mattn/go-runewidth v0.0.7
 	github.com/mattn/go-sqlite3 v1.12.0
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/spf13/cobra v0.0.5
+	golang.org/x/crypto v0.0.0-20191206172530-e9b2fee46413
+	golang.org/x/net v0.0.0-20191209160850-c0dbc17a3553
 )
@@ -0,0 +1,673 @@
+// Package render renders the HTML of feed items as text for terminals or as
+// Markdown
+package render
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Styles of text, combined as bits
+const (
+	styleBold style = 1 << iota
+	styleItalic
+	styleCode
+	styleLink
+)
+
+// Kinds of blocks
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockPre
+	blockRule
+)
+
+const (
+	// minWidth is the narrowest width text is wrapped to when prefixes of
+	// lists and quotes leave little room
+	minWidth = 20
+)
+
+type (
+	style     int
+	blockKind int
+
+	// Options of rendering text
+	Options struct {
+		// Width is the width lines are wrapped to, 0 doesn't wrap lines
+		Width int
+		// ANSI styles text with ANSI escape codes
+		ANSI bool
+	}
+
+	// span is a piece of text in a single style, spaces and line breaks are
+	// spans of their own
+	span struct {
+		text  string
+		style style
+		space bool
+		br    bool
+	}
+
+	// block is a paragraph, a heading, preformatted text or a rule. First is
+	// the prefix of its first line and rest the prefix of the other lines,
+	// such as "1. " and "   " of list items. Tight blocks follow each other
+	// without a blank line.
+	block struct {
+		kind        blockKind
+		level       int
+		first, rest string
+		spans       []span
+		text        string
+		tight       bool
+	}
+
+	// prefix is the prefix of the lines of a list item or a quote, the first
+	// prefix is only used on the first line
+	prefix struct {
+		first, rest string
+		used        bool
+	}
+
+	// list is an open list, n is the number of the next item of ordered lists
+	list struct {
+		ordered bool
+		n       int
+	}
+
+	renderer struct {
+		markdown bool
+		opts     Options
+		blocks   []*block
+		current  *block
+		prefixes []*prefix
+		lists    []*list
+		style    style
+		links    []string
+	}
+)
+
+// Text renders HTML as text with lines wrapped to the width. Links and images
+// are numbered and their URLs are listed as footnotes.
+func Text(s string, opts Options) string {
+	r := &renderer{opts: opts}
+	r.render(s)
+
+	return r.text()
+}
+
+// Markdown renders HTML as Markdown
+func Markdown(s string) string {
+	r := &renderer{markdown: true}
+	r.render(s)
+
+	return r.text()
+}
+
+// render parses HTML into blocks
+func (r *renderer) render(s string) {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		// The parser recovers from invalid HTML, errors are from reading
+		nodes = []*html.Node{{Type: html.TextNode, Data: s}}
+	}
+
+	for _, n := range nodes {
+		r.walk(n)
+	}
+
+	r.endBlock()
+}
+
+// walk renders a node and its children
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.addText(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.walkChildren(n)
+		return
+	}
+
+	switch n.Data {
+	case "script", "style", "head", "title", "template":
+	case "br":
+		r.inline().spans = append(r.inline().spans, span{br: true})
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.endBlock()
+		r.startBlock(blockHeading).level = int(n.Data[1] - '0')
+		r.withStyle(styleBold, n)
+		r.endBlock()
+	case "pre":
+		r.endBlock()
+		b := r.startBlock(blockPre)
+		b.text = strings.TrimRight(strings.TrimPrefix(textContent(n), "\n"), "\n ")
+		r.current = nil
+		r.blocks = append(r.blocks, b)
+	case "hr":
+		r.endBlock()
+		r.blocks = append(r.blocks, r.startBlock(blockRule))
+		r.current = nil
+	case "blockquote":
+		r.endBlock()
+		r.prefixes = append(r.prefixes, &prefix{first: "> ", rest: "> "})
+		r.walkChildren(n)
+		r.endBlock()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case "ul", "ol":
+		r.endBlock()
+		l := &list{ordered: n.Data == "ol", n: 1}
+		if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+			l.n = start
+		}
+
+		r.lists = append(r.lists, l)
+		r.walkChildren(n)
+		r.endBlock()
+		r.lists = r.lists[:len(r.lists)-1]
+	case "li":
+		r.endBlock()
+		marker := "- "
+		if !r.markdown {
+			marker = "• "
+		}
+
+		if len(r.lists) > 0 && r.lists[len(r.lists)-1].ordered {
+			l := r.lists[len(r.lists)-1]
+			marker = fmt.Sprintf("%d. ", l.n)
+			l.n++
+		}
+
+		r.prefixes = append(r.prefixes, &prefix{first: marker, rest: strings.Repeat(" ", runewidth.StringWidth(marker))})
+		r.walkChildren(n)
+		r.endBlock()
+		r.prefixes = r.prefixes[:len(r.prefixes)-1]
+	case "tr":
+		r.endBlock()
+		r.startBlock(blockParagraph).tight = true
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+
+			if len(r.inline().spans) > 0 {
+				r.addRaw(" |")
+				r.inline().spans = append(r.inline().spans, span{space: true})
+			}
+
+			r.walk(c)
+		}
+		r.endBlock()
+	case "p", "div", "section", "article", "header", "footer", "main", "aside", "nav",
+		"figure", "figcaption", "dl", "dt", "dd", "address", "center", "details", "summary",
+		"table", "thead", "tbody", "tfoot", "caption", "form", "fieldset":
+		r.endBlock()
+		r.walkChildren(n)
+		r.endBlock()
+	case "b", "strong":
+		r.withStyle(styleBold, n)
+	case "i", "em", "cite", "dfn", "var":
+		r.withStyle(styleItalic, n)
+	case "code", "kbd", "samp", "tt":
+		r.withStyle(styleCode, n)
+	case "a":
+		r.link(n)
+	case "img":
+		r.image(n)
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *renderer) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+// withStyle renders the children of a node in a style. Markdown markers are
+// wrapped around the words of the children and not their spaces as Markdown
+// doesn't allow spaces inside markers.
+func (r *renderer) withStyle(s style, n *html.Node) {
+	if r.style&s != 0 {
+		r.walkChildren(n)
+		return
+	}
+
+	var marker string
+	if r.markdown && !(s == styleBold && r.inline().kind == blockHeading) {
+		// Headings are marked by # instead
+		marker = map[style]string{styleBold: "**", styleItalic: "*", styleCode: "`"}[s]
+	}
+
+	r.style |= s
+	r.wrapSpans(n, marker, marker)
+	r.style &^= s
+}
+
+// link renders a link, the URL is listed as a footnote of text or follows the
+// link in Markdown
+func (r *renderer) link(n *html.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		r.walkChildren(n)
+		return
+	}
+
+	if r.markdown {
+		r.wrapSpans(n, "[", "]("+href+")")
+		return
+	}
+
+	// Links showing their URL don't need a footnote
+	if strings.TrimSpace(textContent(n)) == href {
+		r.walkChildren(n)
+		return
+	}
+
+	r.style |= styleLink
+	r.walkChildren(n)
+	r.style &^= styleLink
+	r.addRaw(r.footnote(href))
+}
+
+// image renders an image by its alternative text
+func (r *renderer) image(n *html.Node) {
+	src := strings.TrimSpace(attr(n, "src"))
+	alt := strings.Join(strings.Fields(attr(n, "alt")), " ")
+	if src == "" {
+		return
+	}
+
+	if r.markdown {
+		r.addRaw(fmt.Sprintf("![%s](%s)", escapeMarkdown(alt), src))
+		return
+	}
+
+	label := "[image]"
+	if alt != "" {
+		label = "[image: " + alt + "]"
+	}
+
+	r.addRaw(label + r.footnote(src))
+}
+
+// footnote returns the marker of the footnote of a URL, URLs are numbered in
+// the order they first appear
+func (r *renderer) footnote(u string) string {
+	for i, link := range r.links {
+		if link == u {
+			return fmt.Sprintf("[%d]", i+1)
+		}
+	}
+
+	r.links = append(r.links, u)
+
+	return fmt.Sprintf("[%d]", len(r.links))
+}
+
+// wrapSpans renders the children of a node and wraps the words among them in
+// the open and close markers
+func (r *renderer) wrapSpans(n *html.Node, open, close string) {
+	b := r.inline()
+	start := len(b.spans)
+
+	r.walkChildren(n)
+
+	// A block inside the node has ended the block of the markers
+	if open == "" || r.current != b {
+		return
+	}
+
+	first, last := -1, -1
+	for i := start; i < len(b.spans); i++ {
+		if !b.spans[i].space && !b.spans[i].br {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+
+	if first < 0 {
+		return
+	}
+
+	spans := make([]span, 0, len(b.spans)+2)
+	spans = append(spans, b.spans[:first]...)
+	spans = append(spans, span{text: open})
+	spans = append(spans, b.spans[first:last+1]...)
+	spans = append(spans, span{text: close})
+	spans = append(spans, b.spans[last+1:]...)
+	b.spans = spans
+}
+
+// addText adds the words of text to the current block, whitespace is collapsed
+func (r *renderer) addText(text string) {
+	b := r.inline()
+
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		if text != "" {
+			b.spans = append(b.spans, span{space: true})
+		}
+		return
+	}
+
+	if strings.TrimLeft(text, " \t\n\r\f") != text {
+		b.spans = append(b.spans, span{space: true})
+	}
+
+	for i, word := range words {
+		if i > 0 {
+			b.spans = append(b.spans, span{space: true})
+		}
+
+		if r.markdown && r.style&styleCode == 0 {
+			word = escapeMarkdown(word)
+		}
+
+		b.spans = append(b.spans, span{text: word, style: r.style})
+	}
+
+	if strings.TrimRight(text, " \t\n\r\f") != text {
+		b.spans = append(b.spans, span{space: true})
+	}
+}
+
+// addRaw adds text that is neither escaped nor split into words
+func (r *renderer) addRaw(text string) {
+	b := r.inline()
+	b.spans = append(b.spans, span{text: text, style: r.style &^ styleLink})
+}
+
+// inline returns the current block, a paragraph is started if there is none
+func (r *renderer) inline() *block {
+	if r.current == nil {
+		r.current = r.startBlock(blockParagraph)
+	}
+
+	return r.current
+}
+
+// startBlock starts a block prefixed by the open list items and quotes
+func (r *renderer) startBlock(kind blockKind) *block {
+	b := &block{kind: kind, tight: len(r.lists) > 0}
+
+	for _, p := range r.prefixes {
+		if p.used {
+			b.first += p.rest
+		} else {
+			b.first += p.first
+			p.used = true
+		}
+
+		b.rest += p.rest
+	}
+
+	r.current = b
+
+	return b
+}
+
+// endBlock ends the current block, blocks without any words are dropped
+func (r *renderer) endBlock() {
+	b := r.current
+	r.current = nil
+
+	if b == nil {
+		return
+	}
+
+	for _, s := range b.spans {
+		if !s.space && !s.br {
+			r.blocks = append(r.blocks, b)
+			return
+		}
+	}
+}
+
+// text returns the blocks as text or Markdown separated by blank lines, items
+// of lists and rows of tables are only separated by line breaks
+func (r *renderer) text() string {
+	var lines []string
+	var prev *block
+
+	for _, b := range r.blocks {
+		if prev != nil && !(prev.tight && b.tight) {
+			lines = append(lines, strings.TrimRight(commonPrefix(prev.rest, b.rest), " "))
+		}
+
+		lines = append(lines, r.blockLines(b)...)
+		prev = b
+	}
+
+	if !r.markdown && len(r.links) > 0 {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+
+		for i, link := range r.links {
+			lines = append(lines, fmt.Sprintf("[%d] %s", i+1, link))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// blockLines returns the lines of a block with their prefixes
+func (r *renderer) blockLines(b *block) []string {
+	var lines []string
+
+	switch b.kind {
+	case blockRule:
+		rule := "---"
+		if !r.markdown {
+			rule = strings.Repeat("─", clampWidth(r.opts.Width-runewidth.StringWidth(b.first), 20, 40))
+		}
+
+		return []string{b.first + rule}
+	case blockPre:
+		code := strings.Split(b.text, "\n")
+		if r.markdown {
+			code = append(append([]string{"```"}, code...), "```")
+		}
+
+		for i, line := range code {
+			if !r.markdown {
+				line = "    " + line
+			}
+
+			p := b.rest
+			if i == 0 {
+				p = b.first
+			}
+
+			lines = append(lines, strings.TrimRight(p+line, " "))
+		}
+
+		return lines
+	}
+
+	width := 0
+	if r.opts.Width > 0 && !r.markdown {
+		width = clampWidth(r.opts.Width-runewidth.StringWidth(b.rest), minWidth, r.opts.Width)
+	}
+
+	for i, line := range r.wrap(b.spans, width) {
+		p := b.rest
+		if i == 0 {
+			p = b.first
+
+			if b.kind == blockHeading && r.markdown {
+				p += strings.Repeat("#", b.level) + " "
+			}
+		}
+
+		lines = append(lines, p+line)
+	}
+
+	return lines
+}
+
+// wrap joins spans into lines no wider than the width, words wider than the
+// width are put on their own line. A width of 0 doesn't wrap lines.
+func (r *renderer) wrap(spans []span, width int) []string {
+	var lines []string
+	var line strings.Builder
+	var lineWidth int
+	var word []span
+	var space bool
+
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+
+		var w int
+		for _, s := range word {
+			w += runewidth.StringWidth(s.text)
+		}
+
+		if lineWidth > 0 && space {
+			if width > 0 && lineWidth+1+w > width {
+				lines = append(lines, line.String())
+				line.Reset()
+				lineWidth = 0
+			} else {
+				line.WriteByte(' ')
+				lineWidth++
+			}
+		}
+
+		for _, s := range word {
+			line.WriteString(r.styled(s))
+		}
+
+		lineWidth += w
+		word = nil
+		space = false
+	}
+
+	for _, s := range spans {
+		switch {
+		case s.br:
+			flushWord()
+			lines = append(lines, line.String())
+			line.Reset()
+			lineWidth = 0
+			space = false
+		case s.space:
+			flushWord()
+			space = true
+		default:
+			word = append(word, s)
+		}
+	}
+
+	flushWord()
+
+	return append(lines, line.String())
+}
+
+// styled returns the text of a span with ANSI escape codes of its style
+func (r *renderer) styled(s span) string {
+	if !r.opts.ANSI || r.markdown || s.style == 0 {
+		return s.text
+	}
+
+	var codes []string
+	if s.style&styleBold != 0 {
+		codes = append(codes, "1")
+	}
+	if s.style&styleItalic != 0 {
+		codes = append(codes, "3")
+	}
+	if s.style&styleLink != 0 {
+		codes = append(codes, "4")
+	}
+	if s.style&styleCode != 0 {
+		codes = append(codes, "36")
+	}
+
+	return fmt.Sprintf("\x1b[%sm%s\x1b[0m", strings.Join(codes, ";"), s.text)
+}
+
+// attr returns the value of an attribute of a node
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// textContent returns the text of a node and its children
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "br" {
+			b.WriteByte('\n')
+			continue
+		}
+
+		b.WriteString(textContent(c))
+	}
+
+	return b.String()
+}
+
+// escapeMarkdown escapes the characters of text that are markup in Markdown
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+
+	for _, c := range text {
+		if strings.ContainsRune("\\`*_[]", c) {
+			b.WriteByte('\\')
+		}
+
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// commonPrefix returns the longest prefix of both strings
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return a[:i]
+}
+
+// clampWidth returns the width bounded by min and max
+func clampWidth(width, min, max int) int {
+	if width > max {
+		width = max
+	}
+	if width < min {
+		width = min
+	}
+
+	return width
+}
@@ -0,0 +1,65 @@
+package render_test
+
+import (
+	"testing"
+
+	"feeda/render"
+)
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		html     string
+		opts     render.Options
+		expected string
+	}{
+		{"", render.Options{}, ""},
+		{"Plain &amp; simple", render.Options{}, "Plain & simple"},
+		{"<p>One</p><p>Two</p>", render.Options{}, "One\n\nTwo"},
+		{"<p>Hello <b>big</b>ger  world</p>", render.Options{}, "Hello bigger world"},
+		{"<p>The quick brown fox jumps over the lazy dog</p>", render.Options{Width: 20}, "The quick brown fox\njumps over the lazy\ndog"},
+		{"Line<br>break", render.Options{}, "Line\nbreak"},
+		{"<h1>Title</h1>Text", render.Options{}, "Title\n\nText"},
+		{"<p>See <a href=\"http://a\">this</a> and <a href=\"http://b\">that</a> or <a href=\"http://a\">this</a></p>", render.Options{},
+			"See this[1] and that[2] or this[1]\n\n[1] http://a\n[2] http://b"},
+		{"<a href=\"http://a\">http://a</a>", render.Options{}, "http://a"},
+		{"<img src=\"http://i\" alt=\"A cat\">", render.Options{}, "[image: A cat][1]\n\n[1] http://i"},
+		{"<ul><li>One</li><li>Two<ol start=\"3\"><li>Three</li></ol></li></ul>", render.Options{}, "• One\n• Two\n  3. Three"},
+		{"<ol><li>A long item to wrap around</li></ol>", render.Options{Width: 24}, "1. A long item to wrap\n   around"},
+		{"<blockquote><p>Quoted</p><p>Twice</p></blockquote>", render.Options{}, "> Quoted\n>\n> Twice"},
+		{"<pre>if a {\n  b()\n}</pre>", render.Options{Width: 5}, "    if a {\n      b()\n    }"},
+		{"<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>", render.Options{}, "A | B\n1 | 2"},
+		{"<script>alert(1)</script><style>p {}</style>Text", render.Options{}, "Text"},
+		{"<b>Bold</b> <code>code</code>", render.Options{ANSI: true}, "\x1b[1mBold\x1b[0m \x1b[36mcode\x1b[0m"},
+		{"<a href=\"http://a\">Link</a>", render.Options{ANSI: true}, "\x1b[4mLink\x1b[0m[1]\n\n[1] http://a"},
+	}
+
+	for i, test := range tests {
+		actual := render.Text(test.html, test.opts)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		html     string
+		expected string
+	}{
+		{"", ""},
+		{"<h2>Title</h2><p>Some <b>bold </b>and <em>italic</em> text</p>", "## Title\n\nSome **bold** and *italic* text"},
+		{"<p>Use <code>a_b</code> not a_b</p>", "Use `a_b` not a\\_b"},
+		{"<a href=\"http://a\">A <i>link</i></a>", "[A *link*](http://a)"},
+		{"<img src=\"http://i\" alt=\"Cat\">", "![Cat](http://i)"},
+		{"<ol><li>One</li><li>Two</li></ol>", "1. One\n2. Two"},
+		{"<blockquote>Quote</blockquote><hr>", "> Quote\n\n---"},
+		{"<pre>code\n</pre>", "```\ncode\n```"},
+	}
+
+	for i, test := range tests {
+		actual := render.Markdown(test.html)
+		if actual != test.expected {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+}
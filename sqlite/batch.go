@@ -0,0 +1,25 @@
+package sqlite
+
+const (
+	// maxParams is the limit of parameters in a statement of SQLite
+	maxParams = 999
+)
+
+// Batches calls fn with the bounds of consecutive batches of at most size
+// elements out of n, such as to stay below the limit of parameters in a
+// statement. Stops at the first error and returns it.
+func Batches(n, size int, fn func(start, end int) error) error {
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+
+		err := fn(start, end)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	itemCategoriesTable = "item_categories"
+)
+
+// upsertItemCategories persists the categories of items, the items are looked
+// up by their feed and GUID as their IDs aren't known after a bulk insert.
+// Categories of items that haven't been persisted are skipped.
+func upsertItemCategories(db cruderExecer, items ...Item) error {
+	var rows [][]interface{}
+
+	for _, item := range items {
+		for _, name := range item.Categories {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+
+			rows = append(rows, []interface{}{item.FeedID, item.GUID, name})
+		}
+	}
+
+	// Items can have any number of categories
+	return Batches(len(rows), maxParams/3, func(start, end int) error {
+		var values []string
+		var params []interface{}
+
+		for _, row := range rows[start:end] {
+			values = append(values, "(?, ?, ?)")
+			params = append(params, row...)
+		}
+
+		_, err := db.Exec(
+			// WHERE is required to parse ON CONFLICT after a join
+			fmt.Sprintf(`INSERT INTO "%s" (item_id, name)
+			SELECT i.id, v.column3 FROM (VALUES %s) v
+				JOIN "%s" i ON i.feed_id = v.column1 AND i.guid = v.column2 WHERE true
+			ON CONFLICT (item_id, name) DO NOTHING`,
+				itemCategoriesTable, strings.Join(values, ","), itemsTable,
+			),
+			params...,
+		)
+
+		return err
+	})
+}
+
+// ListItemCategories returns the categories of items by the IDs of the items
+func ListItemCategories(db cruderQueryer, ids ...int64) (map[int64][]string, error) {
+	categories := make(map[int64][]string)
+
+	err := Batches(len(ids), maxParams, func(start, end int) error {
+		return listItemCategories(db, categories, ids[start:end]...)
+	})
+
+	return categories, err
+}
+
+// listItemCategories adds the categories of items to the map in a single
+// statement
+func listItemCategories(db cruderQueryer, categories map[int64][]string, ids ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	rows, err := db.Query(
+		fmt.Sprintf(`SELECT item_id, name FROM "%s" WHERE item_id IN (%s) ORDER BY item_id, name`, itemCategoriesTable, strings.Join(placeholders, ",")),
+		params...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var name string
+
+		err = rows.Scan(&id, &name)
+		if err != nil {
+			return err
+		}
+
+		categories[id] = append(categories[id], name)
+	}
+
+	return rows.Err()
+}
@@ -2,9 +2,13 @@ package sqlite_test
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"path"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -72,7 +76,7 @@ func TestCRUD(t *testing.T) {
 
 	// List feeds
 	var feeds []*sqlite.Feed
-	feeds, err = sqlite.ListFeeds(db)
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -118,7 +122,7 @@ func TestCRUD(t *testing.T) {
 	}
 
 	// List feeds
-	feeds, err = sqlite.ListFeeds(db, feeds[0].ID, feeds[1].ID)
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feeds[0].ID, feeds[1].ID}})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -132,7 +136,7 @@ func TestCRUD(t *testing.T) {
 	}
 
 	// Add item 1
-	affected, err := sqlite.CreateIgnoreItems(db, sqlite.Item{
+	affected, err := sqlite.UpsertItems(db, sqlite.Item{
 		FeedID:      feeds[0].ID,
 		GUID:        testItemGUID,
 		URL:         testItemURL,
@@ -147,8 +151,8 @@ func TestCRUD(t *testing.T) {
 		t.Fatalf("expecting affected to be 1, got %d", affected)
 	}
 
-	// Add item 2 and 3 and should ignore item 1
-	affected, err = sqlite.CreateIgnoreItems(db,
+	// Add item 2 and 3 and should ignore item 1 as it is unchanged
+	affected, err = sqlite.UpsertItems(db,
 		sqlite.Item{
 			FeedID:      feeds[0].ID,
 			GUID:        testItemGUID,
@@ -325,7 +329,7 @@ func TestCRUD(t *testing.T) {
 	}
 
 	// List feeds again should return empty list
-	feeds, err = sqlite.ListFeeds(db)
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -334,6 +338,1319 @@ func TestCRUD(t *testing.T) {
 	}
 }
 
+func TestFeedCacheHeaders(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 {
+		t.Fatalf("expecting length of feeds to be 1, got %d", len(feeds))
+	}
+	if feeds[0].ETag != "" || feeds[0].LastModified != "" {
+		t.Fatalf("expecting empty cache headers, got %q and %q", feeds[0].ETag, feeds[0].LastModified)
+	}
+
+	etag := `W/"abc"`
+	lastModified := "Mon, 02 Jan 2006 15:04:05 GMT"
+	err = sqlite.SetFeedCacheHeaders(db, feeds[0].ID, etag, lastModified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feeds[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].ETag != etag {
+		t.Fatalf("expecting etag to be %s, got %s", etag, feeds[0].ETag)
+	}
+	if feeds[0].LastModified != lastModified {
+		t.Fatalf("expecting last_modified to be %s, got %s", lastModified, feeds[0].LastModified)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeedErrors(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].ErrorCount != 0 || feeds[0].LastErrorAt != nil {
+		t.Fatalf("expecting no errors, got %d", feeds[0].ErrorCount)
+	}
+
+	// Record two consecutive failures
+	err = sqlite.SetFeedError(db, feeds[0].ID, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sqlite.SetFeedError(db, feeds[0].ID, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feeds[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].ErrorCount != 2 {
+		t.Fatalf("expecting error_count to be 2, got %d", feeds[0].ErrorCount)
+	}
+	if feeds[0].LastError != "second" {
+		t.Fatalf("expecting last_error to be second, got %s", feeds[0].LastError)
+	}
+	if feeds[0].LastErrorAt == nil {
+		t.Fatal("expecting last_error_at to be set")
+	}
+
+	// A successful sync resets the consecutive failures
+	err = sqlite.SetFeedsSyncedAtNow(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feeds[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].ErrorCount != 0 {
+		t.Fatalf("expecting error_count to be 0, got %d", feeds[0].ErrorCount)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeedMeta(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS, Title: "title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].Title != "title" {
+		t.Fatalf("expecting title of feed to be title, got %s", feeds[0].Title)
+	}
+
+	feed := *feeds[0]
+	feed.Title = "new title"
+	feed.Link = testFeedURL + "/blog"
+	feed.Description = "desc"
+	feed.Icon = testFeedURL + "/icon.png"
+	feed.UpdateInterval = time.Hour
+	err = sqlite.SetFeedMeta(db, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feed.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].Title != feed.Title || feeds[0].Link != feed.Link || feeds[0].Description != feed.Description || feeds[0].Icon != feed.Icon || feeds[0].UpdateInterval != feed.UpdateInterval {
+		t.Fatalf("expecting metadata of feed to be %+v, got %+v", feed, feeds[0])
+	}
+
+	// Items have the title of their feed
+	_, err = sqlite.UpsertItems(db, sqlite.Item{
+		FeedID:      feed.ID,
+		GUID:        testItemGUID,
+		URL:         testItemURL,
+		PublishedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].FeedTitle != feed.Title {
+		t.Fatalf("expecting one item with feed title %s, got %+v", feed.Title, items)
+	}
+
+	err = sqlite.DeleteFeeds(db, feed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpsertItems(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db,
+		sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS},
+		sqlite.Feed{URL: testFeedURL2, Type: sqlite.FeedTypeRSS},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same item in two feeds is kept in both
+	start := time.Now()
+	affected, err := sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, Title: testItemTitle, Desc: testItemDesc, PublishedAt: start},
+		sqlite.Item{FeedID: feeds[1].ID, GUID: testItemGUID, URL: testItemURL, Title: testItemTitle, Desc: testItemDesc, PublishedAt: start},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 2 {
+		t.Fatalf("expecting affected to be 2, got %d", affected)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].UpdatedAt != nil {
+		t.Fatalf("expecting updated_at to be nil, got %s", items[0].UpdatedAt)
+	}
+
+	err = sqlite.SetItemsAsReadNow(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changed item is updated and stays read
+	affected, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, Title: testItemTitle2, Desc: testItemDesc2, PublishedAt: start},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 1 {
+		t.Fatalf("expecting affected to be 1, got %d", affected)
+	}
+
+	updated, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || updated[0].ID != items[0].ID {
+		t.Fatalf("expecting item %d to be updated, got %+v", items[0].ID, updated)
+	}
+	if updated[0].Title != testItemTitle2 || updated[0].Desc != testItemDesc2 {
+		t.Fatalf("expecting title and desc to be %s and %s, got %s and %s", testItemTitle2, testItemDesc2, updated[0].Title, updated[0].Desc)
+	}
+	if updated[0].UpdatedAt == nil {
+		t.Fatal("expecting updated_at to be set")
+	}
+	if updated[0].ReadAt == nil {
+		t.Fatal("expecting read_at to be kept")
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID, feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnreadItems(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, PublishedAt: time.Now()},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsReadNow(db, items[0].ID, items[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsUnread(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unread, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID, ReadStatus: sqlite.ItemUnread})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].ID != items[0].ID || unread[0].ReadAt != nil {
+		t.Fatalf("expecting item %d to be unread, got %+v", items[0].ID, unread)
+	}
+
+	err = sqlite.SetItemsAsUnread(db)
+	if err == nil {
+		t.Fatal("expecting an error for missing IDs")
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListItemsPaged(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Hour)
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, PublishedAt: start},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: start.Add(time.Minute)},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID3, URL: testItemURL3, PublishedAt: start.Add(2 * time.Minute)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		limit, offset int64
+		expected      []string
+	}{
+		{0, 0, []string{testItemGUID, testItemGUID2, testItemGUID3}},
+		{2, 0, []string{testItemGUID, testItemGUID2}},
+		{1, 1, []string{testItemGUID2}},
+		{2, 2, []string{testItemGUID3}},
+		{1, 3, nil},
+	}
+
+	for i, test := range tests {
+		items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID, Limit: test.limit, Offset: test.offset})
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+
+		var actual []string
+		for _, item := range items {
+			actual = append(actual, item.GUID)
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %q, got %q", i, test.expected, actual)
+		}
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnclosures(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := sqlite.Enclosure{URL: testFeedURL + "/episode.mp3", MIMEType: "audio/mpeg", Length: 1024, Duration: 90}
+	_, err = sqlite.UpsertItems(db, sqlite.Item{
+		FeedID:      feeds[0].ID,
+		GUID:        testItemGUID,
+		URL:         testItemURL,
+		PublishedAt: time.Now(),
+		Enclosures:  []sqlite.Enclosure{enc},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enclosures, err := sqlite.ListEnclosures(db, sqlite.EnclosureFilter{ItemIDs: []int64{items[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 {
+		t.Fatalf("expecting length of enclosures to be 1, got %d", len(enclosures))
+	}
+	if enclosures[0].ItemID != items[0].ID {
+		t.Fatalf("expecting item_id of enclosure to be %d, got %d", items[0].ID, enclosures[0].ItemID)
+	}
+	if enclosures[0].URL != enc.URL || enclosures[0].MIMEType != enc.MIMEType || enclosures[0].Length != enc.Length || enclosures[0].Duration != enc.Duration {
+		t.Fatalf("expecting enclosure to be %+v, got %+v", enc, enclosures[0])
+	}
+	if enclosures[0].DownloadedAt != nil {
+		t.Fatalf("expecting downloaded_at to be nil, got %s", enclosures[0].DownloadedAt)
+	}
+
+	err = sqlite.SetEnclosureDownloadedNow(db, enclosures[0].ID, "/tmp/episode.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enclosures, err = sqlite.ListEnclosures(db, sqlite.EnclosureFilter{NotDownloaded: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 0 {
+		t.Fatalf("expecting no enclosures to download, got %d", len(enclosures))
+	}
+
+	enclosures, err = sqlite.ListEnclosures(db, sqlite.EnclosureFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1 || enclosures[0].Path != "/tmp/episode.mp3" || enclosures[0].DownloadedAt == nil {
+		t.Fatalf("expecting enclosure to be downloaded, got %+v", enclosures)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManyEnclosures(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More enclosures and items than parameters allowed in a statement
+	var items []sqlite.Item
+	for i := 0; i < 1100; i++ {
+		item := sqlite.Item{
+			FeedID:      feeds[0].ID,
+			GUID:        fmt.Sprintf("%s%d", testItemGUID, i),
+			URL:         fmt.Sprintf("%s/%d", testFeedURL, i),
+			PublishedAt: time.Now(),
+			Enclosures:  []sqlite.Enclosure{{URL: fmt.Sprintf("%s/%d.mp3", testFeedURL, i)}},
+		}
+
+		if i == 0 {
+			for j := 1; j < 200; j++ {
+				item.Enclosures = append(item.Enclosures, sqlite.Enclosure{URL: fmt.Sprintf("%s/0-%d.mp3", testFeedURL, j)})
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	_, err = sqlite.UpsertItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, item := range listed {
+		ids = append(ids, item.ID)
+	}
+
+	enclosures, err := sqlite.ListEnclosures(db, sqlite.EnclosureFilter{ItemIDs: ids})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enclosures) != 1299 {
+		t.Fatalf("expecting length of enclosures to be 1299, got %d", len(enclosures))
+	}
+
+	for i := 1; i < len(enclosures); i++ {
+		if enclosures[i].ItemID < enclosures[i-1].ItemID {
+			t.Fatalf("expecting enclosures ordered by item, got item %d after %d", enclosures[i].ItemID, enclosures[i-1].ItemID)
+		}
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestItemCategories(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := sqlite.Item{
+		FeedID:      feeds[0].ID,
+		GUID:        testItemGUID,
+		URL:         testItemURL,
+		PublishedAt: time.Now(),
+		Categories:  []string{"Go", " ", "Databases"},
+	}
+
+	_, err = sqlite.UpsertItems(db, item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Categories that already exist are ignored
+	item.Categories = []string{"Go"}
+	_, err = sqlite.UpsertItems(db, item)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	categories, err := sqlite.ListItemCategories(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(categories[items[0].ID], []string{"Databases", "Go"}) {
+		t.Fatalf("expecting categories to be [Databases Go], got %v", categories[items[0].ID])
+	}
+
+	categories, err = sqlite.ListItemCategories(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 0 {
+		t.Fatalf("expecting no categories without IDs, got %v", categories)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManyItemCategories(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// More categories and items than parameters allowed in a statement
+	var items []sqlite.Item
+	for i := 0; i < 1100; i++ {
+		item := sqlite.Item{
+			FeedID:      feeds[0].ID,
+			GUID:        fmt.Sprintf("%s%d", testItemGUID, i),
+			URL:         fmt.Sprintf("%s/%d", testFeedURL, i),
+			PublishedAt: time.Now(),
+			Categories:  []string{"News"},
+		}
+
+		if i == 0 {
+			for j := 1; j < 400; j++ {
+				item.Categories = append(item.Categories, fmt.Sprintf("Category %d", j))
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	_, err = sqlite.UpsertItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []int64
+	for _, item := range listed {
+		ids = append(ids, item.ID)
+	}
+
+	categories, err := sqlite.ListItemCategories(db, ids...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 1100 {
+		t.Fatalf("expecting categories of 1100 items, got %d", len(categories))
+	}
+
+	var total int
+	for _, names := range categories {
+		total += len(names)
+	}
+	if total != 1499 {
+		t.Fatalf("expecting 1499 categories, got %d", total)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchItems(t *testing.T) {
+	_, err = sqlite.SearchItems(db, "title", sqlite.ItemFilter{})
+	if err == sqlite.ErrSearchUnavailable {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, Title: "Release of SQLite", Desc: "Adds full-text search", Author: "Richard", PublishedAt: time.Now()},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, Title: "Release of Go", Desc: "Search the docs", PublishedAt: time.Now()},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID3, URL: testItemURL3, Title: "Weekly news", Desc: "Nothing new", PublishedAt: time.Now()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		urls  []string
+	}{
+		{"release", []string{testItemURL, testItemURL2}},
+		{`"full-text search"`, []string{testItemURL}},
+		{"sea*", []string{testItemURL, testItemURL2}},
+		{"release NOT sqlite", []string{testItemURL2}},
+		{"author:richard", []string{testItemURL}},
+		{"postgres", nil},
+	}
+
+	for _, test := range tests {
+		items, err := sqlite.SearchItems(db, test.query, sqlite.ItemFilter{})
+		if err != nil {
+			t.Fatalf("%s: %s", test.query, err)
+		}
+
+		urls := make(map[string]bool)
+		for _, item := range items {
+			urls[item.URL] = true
+		}
+
+		if len(urls) != len(test.urls) {
+			t.Fatalf("%s: expecting items %v, got %v", test.query, test.urls, urls)
+		}
+		for _, u := range test.urls {
+			if !urls[u] {
+				t.Fatalf("%s: expecting items %v, got %v", test.query, test.urls, urls)
+			}
+		}
+	}
+
+	// The index should follow updates of items
+	_, err = sqlite.UpsertItems(db, sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID3, URL: testItemURL3, Title: "Weekly release", Desc: "Nothing new", PublishedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsReadNow(db, mustSearch(t, "sqlite")[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.SearchItems(db, "release", sqlite.ItemFilter{ReadStatus: sqlite.ItemUnread})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expecting 2 unread items to match, got %d", len(items))
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if items := mustSearch(t, "release"); len(items) != 0 {
+		t.Fatalf("expecting deleted items to not match, got %d", len(items))
+	}
+}
+
+func TestSearchTriggerUpgrade(t *testing.T) {
+	_, err = sqlite.SearchItems(db, "title", sqlite.ItemFilter{})
+	if err == sqlite.ErrSearchUnavailable {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Older versions kept the index in sync on updates of any column
+	_, err = db.Exec(`DROP TRIGGER items_fts_au;
+		CREATE TRIGGER items_fts_au AFTER UPDATE ON items BEGIN
+			INSERT INTO items_fts (items_fts, rowid, title, desc, author) VALUES ('delete', old.id, old.title, old.desc, old.author);
+			INSERT INTO items_fts (rowid, title, desc, author) VALUES (new.id, new.title, new.desc, new.author);
+		END;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.EnsureTables(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var trigger string
+	err = db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'items_fts_au'`).Scan(&trigger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(trigger, "AFTER UPDATE OF title, desc, author ON") {
+		t.Fatalf("expecting update trigger to be limited to indexed columns, got %q", trigger)
+	}
+}
+
+func mustSearch(t *testing.T, query string) []*sqlite.Item {
+	items, err := sqlite.SearchItems(db, query, sqlite.ItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return items
+}
+
+func TestTags(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db,
+		sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS},
+		sqlite.Feed{URL: testFeedURL2, Type: sqlite.FeedTypeAtom},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, PublishedAt: time.Now()},
+		sqlite.Item{FeedID: feeds[1].ID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.TagFeeds(db, "news", feeds[0].ID, feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags are case-insensitive
+	err = sqlite.TagFeeds(db, "Tech", feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.TagFeeds(db, "tech", feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.TagFeeds(db, "a,b", feeds[1].ID)
+	if err == nil {
+		t.Fatal("expecting error for tag with comma")
+	}
+
+	tagged, err := sqlite.ListFeeds(db, sqlite.FeedFilter{Tag: "TECH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 1 || tagged[0].ID != feeds[1].ID {
+		t.Fatalf("expecting only feed %d to be tagged with tech, got %v", feeds[1].ID, tagged)
+	}
+	if len(tagged[0].Tags) != 2 || tagged[0].Tags[0] != "news" || tagged[0].Tags[1] != "Tech" {
+		t.Fatalf("expecting tags of feed to be [news Tech], got %v", tagged[0].Tags)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{Tag: "tech"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].URL != testItemURL2 {
+		t.Fatalf("expecting only item %s to be tagged with tech, got %v", testItemURL2, items)
+	}
+
+	err = sqlite.SetItemsAsReadNow(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := sqlite.ListTags(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("expecting length of tags to be 2, got %d", len(tags))
+	}
+	if tags[0].Name != "news" || tags[0].Feeds != 2 || tags[0].Unread != 1 {
+		t.Fatalf("expecting news to have 2 feeds and 1 unread item, got %+v", tags[0])
+	}
+	if tags[1].Name != "Tech" || tags[1].Feeds != 1 || tags[1].Unread != 0 {
+		t.Fatalf("expecting Tech to have 1 feed and 0 unread items, got %+v", tags[1])
+	}
+
+	err = sqlite.UntagFeeds(db, "tech", feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err = sqlite.ListTags(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Name != "news" {
+		t.Fatalf("expecting only tag news to be left, got %v", tags)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID, feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStarredItems(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, PublishedAt: time.Now().Add(-2 * time.Hour)},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now().Add(-1 * time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsStarredNow(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, err := sqlite.ListItems(db, sqlite.ItemFilter{StarStatus: sqlite.ItemStarred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 1 || starred[0].ID != items[0].ID || starred[0].StarredAt == nil {
+		t.Fatalf("expecting only item %d to be starred, got %v", items[0].ID, starred)
+	}
+
+	unstarred, err := sqlite.ListItems(db, sqlite.ItemFilter{StarStatus: sqlite.ItemUnstarred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unstarred) != 1 || unstarred[0].ID != items[1].ID {
+		t.Fatalf("expecting only item %d to be unstarred, got %v", items[1].ID, unstarred)
+	}
+
+	// Starred items should be kept
+	err = sqlite.DeleteItems(db, items[0].ID, items[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != items[0].ID {
+		t.Fatalf("expecting only starred item %d to be kept, got %v", items[0].ID, left)
+	}
+
+	err = sqlite.SetItemsAsUnstarred(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	starred, err = sqlite.ListItems(db, sqlite.ItemFilter{StarStatus: sqlite.ItemStarred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starred) != 0 {
+		t.Fatalf("expecting no starred items, got %d", len(starred))
+	}
+
+	err = sqlite.SetItemsAsStarredNow(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.ForceDeleteItems(db, items[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	left, err = sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Fatalf("expecting starred item to be deleted when forced, got %d items", len(left))
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadItemsByFilter(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, PublishedAt: time.Now().Add(-10 * 24 * time.Hour)},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now().Add(-1 * time.Hour)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sqlite.SetItemsAsReadNowByFilter(db, sqlite.ItemFilter{
+		IDRanges:        []sqlite.IDRange{{From: items[0].ID, To: items[1].ID}},
+		PublishedBefore: time.Now().Add(-7 * 24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expecting 1 item older than 7 days to be set as read, got %d", n)
+	}
+
+	read, err := sqlite.ListItems(db, sqlite.ItemFilter{ReadStatus: sqlite.ItemRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].ID != items[0].ID {
+		t.Fatalf("expecting only item %d to be read, got %v", items[0].ID, read)
+	}
+
+	// Items that are already read are skipped
+	n, err = sqlite.SetItemsAsReadNowByFilter(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expecting 1 unread item to be set as read, got %d", n)
+	}
+
+	n, err = sqlite.SetItemsAsUnreadByFilter(db, sqlite.ItemFilter{IDs: []int64{items[1].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expecting 1 item to be set as unread, got %d", n)
+	}
+
+	unread, err := sqlite.ListItems(db, sqlite.ItemFilter{ReadStatus: sqlite.ItemUnread})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unread) != 1 || unread[0].ID != items[1].ID {
+		t.Fatalf("expecting only item %d to be unread, got %v", items[1].ID, unread)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestItemContent(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.UpsertItems(db,
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, Desc: "Summary", PublishedAt: time.Now()},
+		sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID2, URL: testItemURL2, Desc: "Summary", PublishedAt: time.Now()},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID, GUIDs: []string{testItemGUID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].GUID != testItemGUID {
+		t.Fatalf("expecting only item with GUID %s, got %v", testItemGUID, items)
+	}
+	if items[0].Body() != "Summary" {
+		t.Fatalf("expecting body to be the description, got %q", items[0].Body())
+	}
+
+	err = sqlite.SetItemContent(db, items[0].ID, "<p>Article</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The content is kept when the item is synced again
+	_, err = sqlite.UpsertItems(db, sqlite.Item{FeedID: feeds[0].ID, GUID: testItemGUID, URL: testItemURL, Desc: "Changed summary", PublishedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err = sqlite.ListItems(db, sqlite.ItemFilter{IDs: []int64{items[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items[0].Content != "<p>Article</p>" || items[0].Desc != "Changed summary" || items[0].Body() != items[0].Content {
+		t.Fatalf("expecting content to be kept apart from the description, got %+v", items[0])
+	}
+
+	missing, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID, NoContent: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 1 || missing[0].GUID != testItemGUID2 {
+		t.Fatalf("expecting only item with GUID %s to have no content, got %v", testItemGUID2, missing)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneItems(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db,
+		sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS},
+		sqlite.Feed{URL: testFeedURL2, Type: sqlite.FeedTypeAtom},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Items are 1 to 4 days old, dates in other time zones should be
+	// compared correctly
+	var items []sqlite.Item
+	for _, feed := range feeds {
+		for days := 1; days <= 4; days++ {
+			items = append(items, sqlite.Item{
+				FeedID:      feed.ID,
+				GUID:        fmt.Sprintf("%d-%d", feed.ID, days),
+				URL:         fmt.Sprintf("%s/%d", feed.URL, days),
+				PublishedAt: time.Now().Add(time.Duration(-days*24+1) * time.Hour).In(time.FixedZone("", 10*3600)),
+			})
+		}
+	}
+
+	_, err = sqlite.UpsertItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listURLs := func(feedID int64) []string {
+		items, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feedID})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var urls []string
+		for _, item := range items {
+			urls = append(urls, item.URL)
+		}
+		sort.Strings(urls)
+
+		return urls
+	}
+
+	// Star the oldest item of the first feed and read the two newest
+	old, err := sqlite.ListItems(db, sqlite.ItemFilter{FeedID: feeds[0].ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsStarredNow(db, old[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.SetItemsAsReadNow(db, old[2].ID, old[3].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Never prune items of the second feed by age
+	noMaxAge := time.Duration(0)
+	keep := int64(3)
+	err = sqlite.SetFeedRetention(db, feeds[1].ID, &noMaxAge, &keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := sqlite.PruneItems(db, sqlite.RetentionPolicy{MaxAge: 48 * time.Hour}, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Pruned != 1 || results[1].Pruned != 1 {
+		t.Fatalf("expecting 1 item to be pruned from each feed, got %+v", results)
+	}
+
+	// Pruned items should not be added again when their feeds are synced
+	items[2].Enclosures = []sqlite.Enclosure{{URL: testFeedURL + "/3.mp3"}}
+	_, err = sqlite.UpsertItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := listURLs(feeds[0].ID)
+	expected := []string{testFeedURL + "/1", testFeedURL + "/2", testFeedURL + "/4"}
+	if fmt.Sprint(urls) != fmt.Sprint(expected) {
+		t.Fatalf("expecting items %v to be kept, got %v", expected, urls)
+	}
+
+	urls = listURLs(feeds[1].ID)
+	expected = []string{testFeedURL2 + "/1", testFeedURL2 + "/2", testFeedURL2 + "/3"}
+	if fmt.Sprint(urls) != fmt.Sprint(expected) {
+		t.Fatalf("expecting items %v to be kept, got %v", expected, urls)
+	}
+
+	// Pruning only read items of a feed without an age or a number of items
+	// to keep would delete all of them, nothing is pruned
+	err = sqlite.SetFeedRetention(db, feeds[1].ID, &noMaxAge, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = sqlite.PruneItems(db, sqlite.RetentionPolicy{MaxAge: time.Hour, ReadOnly: true}, sqlite.FeedFilter{})
+	if err == nil {
+		t.Fatal("expecting an error when a feed overrides the policy to prune every read item")
+	}
+
+	_, err = sqlite.PruneItems(db, sqlite.RetentionPolicy{ReadOnly: true}, sqlite.FeedFilter{IDs: []int64{feeds[0].ID}})
+	if err == nil {
+		t.Fatal("expecting an error when pruning every read item")
+	}
+
+	urls = listURLs(feeds[0].ID)
+	expected = []string{testFeedURL + "/1", testFeedURL + "/2", testFeedURL + "/4"}
+	if fmt.Sprint(urls) != fmt.Sprint(expected) {
+		t.Fatalf("expecting items %v to be kept, got %v", expected, urls)
+	}
+
+	// Only read items
+	results, err = sqlite.PruneItems(db, sqlite.RetentionPolicy{MaxAge: time.Hour, ReadOnly: true}, sqlite.FeedFilter{IDs: []int64{feeds[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Pruned != 2 {
+		t.Fatalf("expecting 2 read items to be pruned, got %+v", results)
+	}
+
+	urls = listURLs(feeds[0].ID)
+	expected = []string{testFeedURL + "/4"}
+	if fmt.Sprint(urls) != fmt.Sprint(expected) {
+		t.Fatalf("expecting items %v to be kept, got %v", expected, urls)
+	}
+
+	// Pruned items that left the feed are forgotten, items 1 and 3 of the
+	// first feed are no longer in it
+	n, err := sqlite.ExpirePrunedItems(db, feeds[0].ID, items[1], items[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expecting 2 pruned items to be forgotten, got %d", n)
+	}
+
+	_, err = sqlite.UpsertItems(db, items[0], items[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls = listURLs(feeds[0].ID)
+	expected = []string{testFeedURL + "/1", testFeedURL + "/4"}
+	if fmt.Sprint(urls) != fmt.Sprint(expected) {
+		t.Fatalf("expecting items %v after forgetting pruned items, got %v", expected, urls)
+	}
+
+	err = sqlite.SetFeedRetention(db, feeds[1].ID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feeds[1].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].RetentionMaxAge != nil || feeds[0].RetentionKeep != nil {
+		t.Fatalf("expecting retention of feed to be cleared, got %v and %v", feeds[0].RetentionMaxAge, feeds[0].RetentionKeep)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = sqlite.DeleteFeeds(db, feeds[0].ID, feeds[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeedPostStats(t *testing.T) {
+	err = sqlite.CreateIgnoreFeeds(db, sqlite.Feed{URL: testFeedURL, Type: sqlite.FeedTypeRSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err := sqlite.ListFeeds(db, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].PostInterval != 0 || feeds[0].NewItemsAt != nil {
+		t.Fatalf("expecting no post stats, got %s and %v", feeds[0].PostInterval, feeds[0].NewItemsAt)
+	}
+
+	feedID := feeds[0].ID
+	items := []sqlite.Item{
+		{FeedID: feedID, GUID: testItemGUID, URL: testItemURL, PublishedAt: time.Now()},
+		{FeedID: feedID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now()},
+		// Duplicates in a feed are counted once
+		{FeedID: feedID, GUID: testItemGUID2, URL: testItemURL2, PublishedAt: time.Now()},
+	}
+
+	newItems, err := sqlite.ListNewItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newItems) != 2 || newItems[0].GUID != testItemGUID || newItems[1].GUID != testItemGUID2 {
+		t.Fatalf("expecting items %s and %s to be new, got %+v", testItemGUID, testItemGUID2, newItems)
+	}
+
+	_, err = sqlite.UpsertItems(db, items[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newItems, err = sqlite.ListNewItems(db, items...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newItems) != 1 || newItems[0].GUID != testItemGUID2 {
+		t.Fatalf("expecting item %s to be new, got %+v", testItemGUID2, newItems)
+	}
+
+	newItemsAt := time.Now().Truncate(time.Second)
+	err = sqlite.SetFeedPostStats(db, feedID, 2*time.Hour, newItemsAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feeds, err = sqlite.ListFeeds(db, sqlite.FeedFilter{IDs: []int64{feedID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feeds[0].PostInterval != 2*time.Hour || feeds[0].NewItemsAt == nil || !feeds[0].NewItemsAt.Equal(newItemsAt) {
+		t.Fatalf("expecting post stats to be 2h and %s, got %s and %v", newItemsAt, feeds[0].PostInterval, feeds[0].NewItemsAt)
+	}
+
+	err = sqlite.DeleteFeeds(db, feedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestCleanup(t *testing.T) {
 	err = db.Close()
 	if err != nil {
@@ -342,3 +1659,40 @@ func TestCleanup(t *testing.T) {
 
 	os.Remove(tmpDB)
 }
+
+func TestBatches(t *testing.T) {
+	tests := []struct {
+		n, size  int
+		expected [][2]int
+	}{
+		{0, 3, nil},
+		{2, 3, [][2]int{{0, 2}}},
+		{6, 3, [][2]int{{0, 3}, {3, 6}}},
+		{7, 3, [][2]int{{0, 3}, {3, 6}, {6, 7}}},
+	}
+
+	for i, test := range tests {
+		var actual [][2]int
+		err := sqlite.Batches(test.n, test.size, func(start, end int) error {
+			actual = append(actual, [2]int{start, end})
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%d: expecting %v, got %v", i, test.expected, actual)
+		}
+	}
+
+	// Stops at the first error
+	calls := 0
+	err := sqlite.Batches(7, 3, func(start, end int) error {
+		calls++
+		return fmt.Errorf("batch %d", start)
+	})
+	if err == nil || err.Error() != "batch 0" || calls != 1 {
+		t.Fatalf("expecting the error of the first batch, got %v after %d calls", err, calls)
+	}
+}
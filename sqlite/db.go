@@ -2,8 +2,200 @@ package sqlite
 
 import "fmt"
 
-// EnsureTables will creates the DB tables if not already exists
-func EnsureTables(db cruderExecer) error {
+type (
+	// Migration is a versioned step changing the DB schema
+	Migration struct {
+		Version     int
+		Description string
+		up          func(db cruderExecQueryRower) error
+	}
+)
+
+// migrations are run in order and must never be changed once released, add a
+// new migration to change the schema instead
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create feeds and items tables",
+		up:          createTables,
+	},
+	{
+		Version:     2,
+		Description: "add HTTP cache columns to feeds",
+		up: addColumns(feedsTable, [][2]string{
+			{"etag", `TEXT NOT NULL DEFAULT ''`},
+			{"last_modified", `TEXT NOT NULL DEFAULT ''`},
+		}),
+	},
+	{
+		Version:     3,
+		Description: "add sync error columns to feeds",
+		up: addColumns(feedsTable, [][2]string{
+			{"last_error", `TEXT NOT NULL DEFAULT ''`},
+			{"last_error_at", `TIMESTAMP`},
+			{"error_count", `INTEGER NOT NULL DEFAULT 0`},
+		}),
+	},
+	{
+		Version:     4,
+		Description: "add metadata columns to feeds",
+		up: addColumns(feedsTable, [][2]string{
+			{"title", `TEXT NOT NULL DEFAULT ''`},
+			{"link", `TEXT NOT NULL DEFAULT ''`},
+			{"description", `TEXT NOT NULL DEFAULT ''`},
+			{"icon", `TEXT NOT NULL DEFAULT ''`},
+		}),
+	},
+	{
+		Version:     5,
+		Description: "make GUIDs of items unique per feed and add updated_at to items",
+		up:          rebuildItemsWithFeedGUID,
+	},
+	{
+		Version:     6,
+		Description: "create enclosures table",
+		up:          createEnclosuresTable,
+	},
+	{
+		Version:     7,
+		Description: "add author column to items",
+		up: addColumns(itemsTable, [][2]string{
+			{"author", `TEXT NOT NULL DEFAULT ''`},
+		}),
+	},
+	{
+		Version:     8,
+		Description: "create tags tables",
+		up:          createTagsTables,
+	},
+	{
+		Version:     9,
+		Description: "add starred_at column to items",
+		up: addColumns(itemsTable, [][2]string{
+			{"starred_at", `TIMESTAMP`},
+		}),
+	},
+	{
+		Version:     10,
+		Description: "add retention columns to feeds and create pruned items table",
+		up:          addRetention,
+	},
+	{
+		Version:     11,
+		Description: "add update interval column to feeds",
+		up: addColumns(feedsTable, [][2]string{
+			{"update_interval", `INTEGER NOT NULL DEFAULT 0`},
+		}),
+	},
+	{
+		Version:     12,
+		Description: "add post statistics columns to feeds",
+		up: addColumns(feedsTable, [][2]string{
+			{"post_interval", `INTEGER NOT NULL DEFAULT 0`},
+			{"new_items_at", `TIMESTAMP`},
+		}),
+	},
+	{
+		Version:     13,
+		Description: "create item categories table",
+		up:          createItemCategoriesTable,
+	},
+	{
+		Version:     14,
+		Description: "add content column to items",
+		up: addColumns(itemsTable, [][2]string{
+			{"content", `TEXT NOT NULL DEFAULT ''`},
+		}),
+	},
+}
+
+// EnsureTables will creates the DB tables if not already exists and upgrades
+// the schema of existing DBs
+func EnsureTables(db cruderBeginner) error {
+	_, err := Migrate(db)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`PRAGMA foreign_keys = ON`)
+	if err != nil {
+		return err
+	}
+
+	return ensureSearchIndex(db)
+}
+
+// SchemaVersion returns the version of the DB schema, 0 for new DBs and DBs
+// created before the schema was versioned
+func SchemaVersion(db cruderQueryRower) (int, error) {
+	var version int
+
+	err := db.QueryRow(`PRAGMA user_version`).Scan(&version)
+
+	return version, err
+}
+
+// PendingMigrations returns the migrations that haven't been run on the DB
+func PendingMigrations(db cruderQueryRower) ([]Migration, error) {
+	version, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := migrations[len(migrations)-1].Version
+	if version > latest {
+		return nil, fmt.Errorf("DB schema version %d is newer than the latest known version %d", version, latest)
+	}
+
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate runs the pending migrations in a transaction, either all of them
+// are run or none. Returns the migrations that were run.
+func Migrate(db cruderBeginner) ([]Migration, error) {
+	pending, err := PendingMigrations(db)
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range pending {
+		err = m.up(tx)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("could not run migration %d (%s): %s", m.Version, m.Description, err)
+		}
+
+		// PRAGMA doesn't support parameters
+		_, err = tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, m.Version))
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// createTables creates the initial schema, tables might already exist in DBs
+// created before the schema was versioned
+func createTables(db cruderExecQueryRower) error {
 	_, err := db.Exec(
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
 			"id" INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -37,11 +229,141 @@ func EnsureTables(db cruderExecer) error {
 	_, err = db.Exec(
 		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_item_read_at" ON "%s" ("read_at")`, itemsTable),
 	)
+
+	return err
+}
+
+// rebuildItemsWithFeedGUID recreates the items table with GUIDs unique per
+// feed instead of GUIDs and URLs unique across all feeds, SQLite can't alter
+// constraints of existing tables
+func rebuildItemsWithFeedGUID(db cruderExecQueryRower) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`CREATE TABLE "%[1]s_new" (
+			"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+			"feed_id" INTEGER NOT NULL,
+			"guid" TEXT NOT NULL,
+			"url" TEXT NOT NULL,
+			"title" TEXT,
+			"desc" TEXT,
+			"published_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			"updated_at" TIMESTAMP,
+			"read_at" TIMESTAMP,
+			UNIQUE("feed_id", "guid"),
+			FOREIGN KEY("feed_id") REFERENCES "%[2]s"("id") ON DELETE CASCADE
+		);
+		INSERT INTO "%[1]s_new" (id, feed_id, guid, url, title, desc, published_at, read_at)
+			SELECT id, feed_id, COALESCE(guid, url), url, title, desc, published_at, read_at FROM "%[1]s";
+		DROP TABLE "%[1]s";
+		ALTER TABLE "%[1]s_new" RENAME TO "%[1]s";
+		CREATE INDEX "idx_item_read_at" ON "%[1]s" ("read_at");`, itemsTable, feedsTable),
+	)
+
+	return err
+}
+
+// createEnclosuresTable creates the table of media files attached to items
+func createEnclosuresTable(db cruderExecQueryRower) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`CREATE TABLE "%s" (
+			"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+			"item_id" INTEGER NOT NULL,
+			"url" TEXT NOT NULL,
+			"mime_type" TEXT NOT NULL DEFAULT '',
+			"length" INTEGER NOT NULL DEFAULT 0,
+			"duration" INTEGER NOT NULL DEFAULT 0,
+			"path" TEXT NOT NULL DEFAULT '',
+			"downloaded_at" TIMESTAMP,
+			UNIQUE("item_id", "url"),
+			FOREIGN KEY("item_id") REFERENCES "%s"("id") ON DELETE CASCADE
+		);`, enclosuresTable, itemsTable),
+	)
+
+	return err
+}
+
+// createItemCategoriesTable creates the table of categories of items
+func createItemCategoriesTable(db cruderExecQueryRower) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`CREATE TABLE "%s" (
+			"item_id" INTEGER NOT NULL,
+			"name" TEXT NOT NULL,
+			PRIMARY KEY("item_id", "name"),
+			FOREIGN KEY("item_id") REFERENCES "%s"("id") ON DELETE CASCADE
+		);`, itemCategoriesTable, itemsTable),
+	)
+
+	return err
+}
+
+// createTagsTables creates the tables of tags and the tags of feeds
+func createTagsTables(db cruderExecQueryRower) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`CREATE TABLE "%[1]s" (
+			"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+			"name" TEXT NOT NULL UNIQUE COLLATE NOCASE
+		);
+		CREATE TABLE "%[2]s" (
+			"feed_id" INTEGER NOT NULL,
+			"tag_id" INTEGER NOT NULL,
+			PRIMARY KEY("feed_id", "tag_id"),
+			FOREIGN KEY("feed_id") REFERENCES "%[3]s"("id") ON DELETE CASCADE,
+			FOREIGN KEY("tag_id") REFERENCES "%[1]s"("id") ON DELETE CASCADE
+		);
+		CREATE INDEX "idx_feed_tag_tag_id" ON "%[2]s" ("tag_id");`, tagsTable, feedTagsTable, feedsTable),
+	)
+
+	return err
+}
+
+// addRetention adds the retention overrides of feeds and creates the table of
+// GUIDs of pruned items
+func addRetention(db cruderExecQueryRower) error {
+	err := addColumns(feedsTable, [][2]string{
+		{"retention_max_age", `INTEGER`},
+		{"retention_keep", `INTEGER`},
+	})(db)
 	if err != nil {
 		return err
 	}
 
-	_, err = db.Exec(`PRAGMA foreign_keys = ON`)
+	_, err = db.Exec(
+		fmt.Sprintf(`CREATE TABLE "%s" (
+			"feed_id" INTEGER NOT NULL,
+			"guid" TEXT NOT NULL,
+			PRIMARY KEY("feed_id", "guid"),
+			FOREIGN KEY("feed_id") REFERENCES "%s"("id") ON DELETE CASCADE
+		);`, prunedItemsTable, feedsTable),
+	)
+
+	return err
+}
+
+// addColumns returns a migration step adding columns to a table. Columns that
+// already exist are skipped as they might have been added by unversioned
+// releases.
+func addColumns(table string, columns [][2]string) func(db cruderExecQueryRower) error {
+	return func(db cruderExecQueryRower) error {
+		for _, column := range columns {
+			err := addColumnIfNotExists(db, table, column[0], column[1])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// addColumnIfNotExists adds a column to a table unless the table already has it
+func addColumnIfNotExists(db cruderExecQueryRower, table, column, definition string) error {
+	var exists int64
+
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN "%s" %s`, table, column, definition))
 
 	return err
 }
@@ -0,0 +1,105 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"os"
+	"path"
+	"testing"
+
+	"feeda/sqlite"
+)
+
+func TestMigrate(t *testing.T) {
+	tmpLegacyDB := path.Join(os.TempDir(), "feeda_legacy_test.db")
+	os.Remove(tmpLegacyDB)
+	defer os.Remove(tmpLegacyDB)
+
+	legacyDB, err := sql.Open("sqlite3", tmpLegacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer legacyDB.Close()
+
+	// Schema of DBs created before the schema was versioned
+	_, err = legacyDB.Exec(`CREATE TABLE "feeds" (
+		"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+		"url" TEXT NOT NULL UNIQUE,
+		"type" TEXT NOT NULL,
+		"created_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		"synced_at" TIMESTAMP
+	);
+	CREATE TABLE "items" (
+		"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+		"feed_id" INTEGER NOT NULL,
+		"guid" TEXT UNIQUE,
+		"url" TEXT NOT NULL UNIQUE,
+		"title" TEXT,
+		"desc" TEXT,
+		"published_at" TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		"read_at" TIMESTAMP,
+		FOREIGN KEY("feed_id") REFERENCES "feeds"("id") ON DELETE CASCADE
+	);
+	INSERT INTO "feeds" (url, type) VALUES ('` + testFeedURL + `', 'RSS');
+	INSERT INTO "items" (feed_id, guid, url, title, desc) VALUES (1, '` + testItemGUID + `', '` + testItemURL + `', 'title', 'desc');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := sqlite.PendingMigrations(legacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) == 0 || pending[0].Version != 1 {
+		t.Fatalf("expecting pending migrations to start with version 1, got %+v", pending)
+	}
+
+	migrated, err := sqlite.Migrate(legacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != len(pending) {
+		t.Fatalf("expecting %d migrations to run, got %d", len(pending), len(migrated))
+	}
+
+	version, err := sqlite.SchemaVersion(legacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != pending[len(pending)-1].Version {
+		t.Fatalf("expecting schema version to be %d, got %d", pending[len(pending)-1].Version, version)
+	}
+
+	pending, err = sqlite.PendingMigrations(legacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expecting no pending migrations, got %d", len(pending))
+	}
+
+	// Existing data is kept
+	feeds, err := sqlite.ListFeeds(legacyDB, sqlite.FeedFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feeds) != 1 || feeds[0].URL != testFeedURL {
+		t.Fatalf("expecting the feed %s to be kept, got %+v", testFeedURL, feeds)
+	}
+
+	items, err := sqlite.ListItems(legacyDB, sqlite.ItemFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].GUID != testItemGUID {
+		t.Fatalf("expecting the item %s to be kept, got %+v", testItemGUID, items)
+	}
+
+	// Running the migrations again does nothing
+	migrated, err = sqlite.Migrate(legacyDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 0 {
+		t.Fatalf("expecting no migrations to run, got %d", len(migrated))
+	}
+}
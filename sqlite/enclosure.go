@@ -0,0 +1,163 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	enclosuresTable = "enclosures"
+)
+
+type (
+	// Enclosure is a media file attached to an item, such as the audio of a
+	// podcast episode
+	Enclosure struct {
+		ID       int64  `json:"id"`
+		ItemID   int64  `json:"item_id"`
+		URL      string `json:"url"`
+		MIMEType string `json:"mime_type"`
+		// Length is the size in bytes
+		Length int64 `json:"length"`
+		// Duration is the playing time in seconds
+		Duration     int64      `json:"duration"`
+		Path         string     `json:"path"`
+		DownloadedAt *time.Time `json:"downloaded_at"`
+	}
+
+	// EnclosureFilter is used to filter enclosures in lists
+	EnclosureFilter struct {
+		ItemIDs       []int64
+		NotDownloaded bool
+	}
+)
+
+// upsertEnclosures persists the enclosures of items, the items are looked up
+// by their feed and GUID as their IDs aren't known after a bulk insert.
+// Enclosures of items that haven't been persisted are skipped.
+func upsertEnclosures(db cruderExecer, items ...Item) error {
+	var rows [][]interface{}
+
+	for _, item := range items {
+		for _, enc := range item.Enclosures {
+			rows = append(rows, []interface{}{item.FeedID, item.GUID, enc.URL, enc.MIMEType, enc.Length, enc.Duration})
+		}
+	}
+
+	// Items can have any number of enclosures
+	return Batches(len(rows), maxParams/6, func(start, end int) error {
+		var values []string
+		var params []interface{}
+
+		for _, row := range rows[start:end] {
+			values = append(values, "(?, ?, ?, ?, ?, ?)")
+			params = append(params, row...)
+		}
+
+		_, err := db.Exec(
+			// WHERE is required to parse ON CONFLICT after a join
+			fmt.Sprintf(`INSERT INTO "%s" (item_id, url, mime_type, length, duration)
+			SELECT i.id, v.column3, v.column4, v.column5, v.column6 FROM (VALUES %s) v
+				JOIN "%s" i ON i.feed_id = v.column1 AND i.guid = v.column2 WHERE true
+			ON CONFLICT (item_id, url) DO UPDATE SET
+				mime_type = excluded.mime_type,
+				length = excluded.length,
+				duration = excluded.duration`,
+				enclosuresTable, strings.Join(values, ","), itemsTable,
+			),
+			params...,
+		)
+
+		return err
+	})
+}
+
+// ListEnclosures returns a list of enclosures from DB
+func ListEnclosures(db cruderQueryer, filter EnclosureFilter) ([]*Enclosure, error) {
+	if len(filter.ItemIDs) <= maxParams {
+		return listEnclosures(db, filter)
+	}
+
+	// The IDs are sorted to keep the enclosures ordered by item
+	ids := append([]int64{}, filter.ItemIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var enclosures []*Enclosure
+	err := Batches(len(ids), maxParams, func(start, end int) error {
+		filter.ItemIDs = ids[start:end]
+		found, err := listEnclosures(db, filter)
+		if err != nil {
+			return err
+		}
+
+		enclosures = append(enclosures, found...)
+
+		return nil
+	})
+
+	return enclosures, err
+}
+
+// listEnclosures returns the enclosures matching the filter in a single
+// statement
+func listEnclosures(db cruderQueryer, filter EnclosureFilter) ([]*Enclosure, error) {
+	var enclosures []*Enclosure
+	var wheres, placeholders []string
+	var whereSQL string
+	var params []interface{}
+
+	for _, id := range filter.ItemIDs {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) > 0 {
+		wheres = append(wheres, fmt.Sprintf("item_id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if filter.NotDownloaded {
+		wheres = append(wheres, "downloaded_at IS NULL")
+	}
+
+	if len(wheres) > 0 {
+		whereSQL = " WHERE " + strings.Join(wheres, " AND ")
+	}
+
+	rows, err := db.Query(
+		fmt.Sprintf(`SELECT id, item_id, url, mime_type, length, duration, path, downloaded_at FROM "%s"%s ORDER BY item_id, id`, enclosuresTable, whereSQL),
+		params...,
+	)
+	if err != nil {
+		return enclosures, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e := &Enclosure{}
+		err = rows.Scan(&e.ID, &e.ItemID, &e.URL, &e.MIMEType, &e.Length, &e.Duration, &e.Path, &e.DownloadedAt)
+		if err != nil {
+			return enclosures, err
+		}
+
+		enclosures = append(enclosures, e)
+	}
+
+	return enclosures, nil
+}
+
+// SetEnclosureDownloadedNow records where an enclosure has been downloaded to
+// and sets its downloaded_at column to CURRENT_TIMESTAMP
+func SetEnclosureDownloadedNow(db cruderExecer, id int64, path string) error {
+	if path == "" {
+		return errors.New("missing path of download")
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET path = ?, downloaded_at = CURRENT_TIMESTAMP WHERE id = ?`, enclosuresTable),
+		path, id,
+	)
+
+	return err
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -15,26 +16,50 @@ const (
 const (
 	FeedTypeRSS  feedType = "RSS"
 	FeedTypeAtom feedType = "Atom"
+	FeedTypeJSON feedType = "JSON"
+	FeedTypeRDF  feedType = "RDF"
 )
 
 type (
 	feedType string
 
-	// Feed contains the URL to the RSS/Atom feed
+	// Feed contains the URL to the RSS/RDF/Atom/JSON feed
 	Feed struct {
-		ID        int64
-		URL       string
-		Type      feedType
-		CreatedAt time.Time
-		SyncedAt  *time.Time
+		ID           int64      `json:"id"`
+		URL          string     `json:"url"`
+		Type         feedType   `json:"type"`
+		Title        string     `json:"title"`
+		Link         string     `json:"link"`
+		Description  string     `json:"description"`
+		Icon         string     `json:"icon"`
+		CreatedAt    time.Time  `json:"created_at"`
+		SyncedAt     *time.Time `json:"synced_at"`
+		ETag         string     `json:"etag"`
+		LastModified string     `json:"last_modified"`
+		LastError    string     `json:"last_error"`
+		LastErrorAt  *time.Time `json:"last_error_at"`
+		ErrorCount   int64      `json:"error_count"`
+		// UpdateInterval is how often the feed says it is updated, by its
+		// content or the cache headers of its responses, 0 if unknown
+		UpdateInterval time.Duration `json:"-"`
+		// PostInterval is the average time between new items of the feed, 0
+		// until it is known
+		PostInterval time.Duration `json:"-"`
+		// NewItemsAt is when new items of the feed were last found
+		NewItemsAt *time.Time `json:"new_items_at"`
+		// RetentionMaxAge and RetentionKeep override the retention policy
+		// when pruning items of the feed, nil uses the policy
+		RetentionMaxAge *time.Duration `json:"-"`
+		RetentionKeep   *int64         `json:"retention_keep"`
+		// Tags are only listed by ListFeeds, they are changed with
+		// TagFeeds and UntagFeeds
+		Tags []string `json:"tags"`
 	}
 
 	// FeedFilter is used to filter feeds in lists
 	FeedFilter struct {
-		FeedID     int64
-		ReadStatus itemReadStatus
-		Limit      int64
-		Offset     int64
+		IDs []int64
+		Tag string
 	}
 )
 
@@ -48,12 +73,12 @@ func CreateIgnoreFeeds(db cruderExecer, feeds ...Feed) error {
 	}
 
 	for _, feed := range feeds {
-		values = append(values, "(?, ?)")
-		params = append(params, feed.URL, string(feed.Type))
+		values = append(values, "(?, ?, ?, ?, ?, ?)")
+		params = append(params, feed.URL, string(feed.Type), feed.Title, feed.Link, feed.Description, feed.Icon)
 	}
 
 	_, err := db.Exec(
-		fmt.Sprintf(`INSERT OR IGNORE INTO "%s" (url, type) VALUES %s`, feedsTable, strings.Join(values, ",")),
+		fmt.Sprintf(`INSERT OR IGNORE INTO "%s" (url, type, title, link, description, icon) VALUES %s`, feedsTable, strings.Join(values, ",")),
 		params...,
 	)
 
@@ -61,23 +86,37 @@ func CreateIgnoreFeeds(db cruderExecer, feeds ...Feed) error {
 }
 
 // ListFeeds returns a list of feeds from DB
-func ListFeeds(db cruderQueryer, ids ...int64) ([]*Feed, error) {
+func ListFeeds(db cruderQueryer, filter FeedFilter) ([]*Feed, error) {
 	var feeds []*Feed
-	var wheres []string
+	var wheres, placeholders []string
 	var whereSQL string
 	var params []interface{}
 
-	for _, id := range ids {
-		wheres = append(wheres, "?")
+	for _, id := range filter.IDs {
+		placeholders = append(placeholders, "?")
 		params = append(params, id)
 	}
 
+	if len(placeholders) > 0 {
+		wheres = append(wheres, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if filter.Tag != "" {
+		wheres = append(wheres, fmt.Sprintf("id IN (%s)", feedIDsByTagSQL))
+		params = append(params, filter.Tag)
+	}
+
 	if len(wheres) > 0 {
-		whereSQL = fmt.Sprintf(" WHERE id IN (%s)", strings.Join(wheres, ","))
+		whereSQL = " WHERE " + strings.Join(wheres, " AND ")
 	}
 
 	rows, err := db.Query(
-		fmt.Sprintf(`SELECT id, url, type, created_at, synced_at FROM "%s"%s ORDER BY id`, feedsTable, whereSQL),
+		fmt.Sprintf(
+			`SELECT id, url, type, title, link, description, icon, created_at, synced_at, etag, last_modified, last_error, last_error_at, error_count, update_interval, post_interval, new_items_at, retention_max_age, retention_keep,
+				(SELECT COALESCE(group_concat(name, ','), '') FROM (SELECT t.name FROM "%[1]s" ft JOIN "%[2]s" t ON t.id = ft.tag_id WHERE ft.feed_id = "%[3]s".id ORDER BY t.name))
+			FROM "%[3]s"%[4]s ORDER BY id`,
+			feedTagsTable, tagsTable, feedsTable, whereSQL,
+		),
 		params...,
 	)
 	if err != nil {
@@ -86,13 +125,29 @@ func ListFeeds(db cruderQueryer, ids ...int64) ([]*Feed, error) {
 	defer rows.Close()
 	for rows.Next() {
 		f := &Feed{}
-		var t string
-		err = rows.Scan(&f.ID, &f.URL, &t, &f.CreatedAt, &f.SyncedAt)
+		var t, tags string
+		var updateInterval, postInterval int64
+		var maxAge, keep sql.NullInt64
+		err = rows.Scan(&f.ID, &f.URL, &t, &f.Title, &f.Link, &f.Description, &f.Icon, &f.CreatedAt, &f.SyncedAt, &f.ETag, &f.LastModified, &f.LastError, &f.LastErrorAt, &f.ErrorCount, &updateInterval, &postInterval, &f.NewItemsAt, &maxAge, &keep, &tags)
 		if err != nil {
 			return feeds, err
 		}
 
+		f.UpdateInterval = time.Duration(updateInterval) * time.Second
+		f.PostInterval = time.Duration(postInterval) * time.Second
+
+		if maxAge.Valid {
+			d := time.Duration(maxAge.Int64) * time.Second
+			f.RetentionMaxAge = &d
+		}
+		if keep.Valid {
+			f.RetentionKeep = &keep.Int64
+		}
+
 		f.Type = feedType(t)
+		if tags != "" {
+			f.Tags = strings.Split(tags, ",")
+		}
 
 		feeds = append(feeds, f)
 	}
@@ -100,7 +155,20 @@ func ListFeeds(db cruderQueryer, ids ...int64) ([]*Feed, error) {
 	return feeds, nil
 }
 
+// Retention returns the policy overridden by the retention of the feed
+func (f Feed) Retention(policy RetentionPolicy) RetentionPolicy {
+	if f.RetentionMaxAge != nil {
+		policy.MaxAge = *f.RetentionMaxAge
+	}
+	if f.RetentionKeep != nil {
+		policy.Keep = *f.RetentionKeep
+	}
+
+	return policy
+}
+
 // SetFeedsSyncedAtNow sets the synced_at column of feeds to CURRENT_TIMESTAMP
+// and resets their count of consecutive failures
 func SetFeedsSyncedAtNow(db cruderExecer, ids ...int64) error {
 	var placeholders []string
 	var params []interface{}
@@ -115,13 +183,57 @@ func SetFeedsSyncedAtNow(db cruderExecer, ids ...int64) error {
 	}
 
 	_, err := db.Exec(
-		fmt.Sprintf(`UPDATE "%s" SET synced_at = CURRENT_TIMESTAMP WHERE id IN (%s)`, feedsTable, strings.Join(placeholders, ",")),
+		fmt.Sprintf(`UPDATE "%s" SET synced_at = CURRENT_TIMESTAMP, error_count = 0 WHERE id IN (%s)`, feedsTable, strings.Join(placeholders, ",")),
 		params...,
 	)
 
 	return err
 }
 
+// SetFeedPostStats stores the average time between new items of a feed and
+// when new items were last found
+func SetFeedPostStats(db cruderExecer, id int64, postInterval time.Duration, newItemsAt time.Time) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET post_interval = ?, new_items_at = ? WHERE id = ?`, feedsTable),
+		int64(postInterval/time.Second), newItemsAt.UTC(), id,
+	)
+
+	return err
+}
+
+// SetFeedCacheHeaders stores the ETag and Last-Modified response headers of a
+// feed so they can be used for conditional requests on the next sync
+func SetFeedCacheHeaders(db cruderExecer, id int64, etag, lastModified string) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET etag = ?, last_modified = ? WHERE id = ?`, feedsTable),
+		etag, lastModified, id,
+	)
+
+	return err
+}
+
+// SetFeedMeta updates the title, link, description, icon and update interval
+// of a feed
+func SetFeedMeta(db cruderExecer, feed Feed) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET title = ?, link = ?, description = ?, icon = ?, update_interval = ? WHERE id = ?`, feedsTable),
+		feed.Title, feed.Link, feed.Description, feed.Icon, int64(feed.UpdateInterval/time.Second), feed.ID,
+	)
+
+	return err
+}
+
+// SetFeedError records a failed sync of a feed by storing the error message
+// and incrementing its count of consecutive failures
+func SetFeedError(db cruderExecer, id int64, msg string) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET last_error = ?, last_error_at = CURRENT_TIMESTAMP, error_count = error_count + 1 WHERE id = ?`, feedsTable),
+		msg, id,
+	)
+
+	return err
+}
+
 // DeleteFeeds removes one or more feeds from DB
 func DeleteFeeds(db cruderExecer, ids ...int64) error {
 	var placeholders []string
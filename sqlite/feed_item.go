@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -9,6 +10,13 @@ import (
 
 const (
 	itemsTable = "items"
+
+	// upsertBatchSize is the number of items persisted per statement
+	upsertBatchSize = 100
+
+	// itemColumns are the columns read by scanItems, items are aliased as i
+	// and their feeds as f
+	itemColumns = `i.id, i.feed_id, COALESCE(NULLIF(f.title, ''), f.url), i.guid, i.url, i.title, i.desc, i.content, i.author, i.published_at, i.updated_at, i.read_at, i.starred_at`
 )
 
 // Statuses for whether an item is read or unread
@@ -17,54 +25,180 @@ const (
 	ItemUnread
 )
 
+// Statuses for whether an item is starred or not
+const (
+	ItemStarred itemStarStatus = iota + 1
+	ItemUnstarred
+)
+
 type (
 	itemReadStatus int
+	itemStarStatus int
 
 	// Item is an entry in a feed
 	Item struct {
-		ID          int64
-		FeedID      int64
-		GUID        string
-		URL         string
-		Title       string
-		Desc        string
-		PublishedAt time.Time
-		ReadAt      *time.Time
+		ID          int64      `json:"id"`
+		FeedID      int64      `json:"feed_id"`
+		FeedTitle   string     `json:"feed_title"`
+		GUID        string     `json:"guid"`
+		URL         string     `json:"url"`
+		Title       string     `json:"title"`
+		Desc        string     `json:"desc"`
+		Author      string     `json:"author"`
+		PublishedAt time.Time  `json:"published_at"`
+		UpdatedAt   *time.Time `json:"updated_at"`
+		ReadAt      *time.Time `json:"read_at"`
+		StarredAt   *time.Time `json:"starred_at"`
+		// Content is the article extracted from the page of the item, it
+		// is only set by SetItemContent
+		Content string `json:"content"`
+		// Enclosures are only persisted by UpsertItems, they are listed
+		// with ListEnclosures
+		Enclosures []Enclosure `json:"enclosures"`
+		// Categories are only persisted by UpsertItems, they are listed
+		// with ListItemCategories
+		Categories []string `json:"categories"`
 	}
 
-	// ItemFilter is used to filter feed items in lists
+	// ItemFilter is used to filter feed items in lists, items matching either
+	// IDs or IDRanges are selected
 	ItemFilter struct {
+		IDs        []int64
+		IDRanges   []IDRange
 		FeedID     int64
+		GUIDs      []string
+		Tag        string
 		ReadStatus itemReadStatus
-		Limit      int64
-		Offset     int64
+		StarStatus itemStarStatus
+		// PublishedBefore selects items published before the time unless zero
+		PublishedBefore time.Time
+		Limit           int64
+		Offset          int64
+		// NoContent selects items without an extracted article
+		NoContent bool
+	}
+
+	// IDRange is an inclusive range of item IDs
+	IDRange struct {
+		From, To int64
 	}
 )
 
-// CreateIgnoreItems persists items to DB and if it already exists then skip it
-// returns number of items inserted and error if any
-func CreateIgnoreItems(db cruderExecer, items ...Item) (int64, error) {
-	var values []string
-	var params []interface{}
+// UpsertItems persists items and their enclosures to DB, items that already
+// exist in their feed are updated if their URL, title, description or author have
+// changed. Items that have been pruned are skipped. Returns the number of items inserted or updated and error if any.
+func UpsertItems(db cruderExecer, items ...Item) (int64, error) {
+	var affected int64
 
 	if len(items) == 0 {
 		return 0, errors.New("missing items to create")
 	}
 
-	for _, item := range items {
-		values = append(values, "(?, ?, ?, ?, ?, ?)")
-		params = append(params, item.FeedID, item.GUID, item.URL, item.Title, item.Desc, item.PublishedAt)
+	err := Batches(len(items), upsertBatchSize, func(start, end int) error {
+		var values []string
+		var params []interface{}
+
+		for _, item := range items[start:end] {
+			values = append(values, "(?, ?, ?, ?, ?, ?, ?)")
+			params = append(params, item.FeedID, item.GUID, item.URL, item.Title, item.Desc, item.Author, item.PublishedAt)
+		}
+
+		// Pruned items are skipped as they are still in the feeds
+		r, err := db.Exec(
+			fmt.Sprintf(`INSERT INTO "%s" (feed_id, guid, url, title, desc, author, published_at)
+			SELECT * FROM (VALUES %s) v WHERE NOT EXISTS (SELECT 1 FROM "%s" p WHERE p.feed_id = v.column1 AND p.guid = v.column2)
+			ON CONFLICT (feed_id, guid) DO UPDATE SET
+				url = excluded.url,
+				title = excluded.title,
+				desc = excluded.desc,
+				author = excluded.author,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE url IS NOT excluded.url OR title IS NOT excluded.title OR desc IS NOT excluded.desc OR author IS NOT excluded.author`,
+				itemsTable, strings.Join(values, ","), prunedItemsTable,
+			),
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err := r.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		affected += n
+
+		err = upsertEnclosures(db, items[start:end]...)
+		if err != nil {
+			return err
+		}
+
+		return upsertItemCategories(db, items[start:end]...)
+	})
+
+	return affected, err
+}
+
+// ListNewItems returns the items that haven't been persisted before, pruned
+// items aren't new. Items are returned once in the order they were given.
+func ListNewItems(db cruderQueryer, items ...Item) ([]Item, error) {
+	var newItems []Item
+
+	type key struct {
+		feedID int64
+		guid   string
 	}
 
-	r, err := db.Exec(
-		fmt.Sprintf(`INSERT OR IGNORE INTO "%s" (feed_id, guid, url, title, desc, published_at) VALUES %s`, itemsTable, strings.Join(values, ",")),
-		params...,
-	)
+	isNew := make(map[key]bool)
+
+	err := Batches(len(items), upsertBatchSize, func(start, end int) error {
+		var values []string
+		var params []interface{}
+
+		for _, item := range items[start:end] {
+			values = append(values, "(?, ?)")
+			params = append(params, item.FeedID, item.GUID)
+		}
+
+		rows, err := db.Query(
+			fmt.Sprintf(`SELECT DISTINCT v.column1, v.column2 FROM (VALUES %s) v
+			WHERE NOT EXISTS (SELECT 1 FROM "%s" i WHERE i.feed_id = v.column1 AND i.guid = v.column2)
+				AND NOT EXISTS (SELECT 1 FROM "%s" p WHERE p.feed_id = v.column1 AND p.guid = v.column2)`,
+				strings.Join(values, ","), itemsTable, prunedItemsTable,
+			),
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var k key
+
+			err = rows.Scan(&k.feedID, &k.guid)
+			if err != nil {
+				return err
+			}
+
+			isNew[k] = true
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return r.RowsAffected()
+	for _, item := range items {
+		k := key{item.FeedID, item.GUID}
+		if isNew[k] {
+			newItems = append(newItems, item)
+			delete(isNew, k)
+		}
+	}
+
+	return newItems, nil
 }
 
 // CountTotalByFeed returns the total number of items for a feed
@@ -93,45 +227,120 @@ func CountUnreadByFeed(db cruderQueryRower, feedID int64) (int64, error) {
 
 // ListItems returns a list of items from DB
 func ListItems(db cruderQueryer, filter ItemFilter) ([]*Item, error) {
-	var items []*Item
-	var wheres []string
-	var whereSQL, limitSQL string
+	var whereSQL string
+
+	wheres, params := itemWheres(filter)
+	if len(wheres) > 0 {
+		whereSQL = " WHERE " + strings.Join(wheres, " AND ")
+	}
+
+	rows, err := db.Query(
+		fmt.Sprintf(
+			`SELECT %s FROM "%s" i JOIN "%s" f ON f.id = i.feed_id%s ORDER BY i.published_at%s`,
+			itemColumns, itemsTable, feedsTable, whereSQL, limitSQL(filter),
+		),
+		params...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanItems(rows)
+}
+
+// itemWheres returns the conditions and their parameters of a filter, items
+// are aliased as i
+func itemWheres(filter ItemFilter) ([]string, []interface{}) {
+	var wheres, placeholders, ids []string
 	var params []interface{}
 
+	for _, id := range filter.IDs {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) > 0 {
+		ids = append(ids, fmt.Sprintf("i.id IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	for _, r := range filter.IDRanges {
+		ids = append(ids, "i.id BETWEEN ? AND ?")
+		params = append(params, r.From, r.To)
+	}
+
+	if len(ids) > 0 {
+		wheres = append(wheres, "("+strings.Join(ids, " OR ")+")")
+	}
+
 	if filter.FeedID > 0 {
-		wheres = append(wheres, "feed_id = ?")
+		wheres = append(wheres, "i.feed_id = ?")
 		params = append(params, filter.FeedID)
 	}
 
+	placeholders = nil
+	for _, guid := range filter.GUIDs {
+		placeholders = append(placeholders, "?")
+		params = append(params, guid)
+	}
+
+	if len(placeholders) > 0 {
+		wheres = append(wheres, fmt.Sprintf("i.guid IN (%s)", strings.Join(placeholders, ",")))
+	}
+
+	if filter.NoContent {
+		wheres = append(wheres, "i.content = ''")
+	}
+
+	if filter.Tag != "" {
+		wheres = append(wheres, fmt.Sprintf("i.feed_id IN (%s)", feedIDsByTagSQL))
+		params = append(params, filter.Tag)
+	}
+
 	if filter.ReadStatus == ItemRead {
-		wheres = append(wheres, "read_at IS NOT NULL")
+		wheres = append(wheres, "i.read_at IS NOT NULL")
 	} else if filter.ReadStatus == ItemUnread {
-		wheres = append(wheres, "read_at IS NULL")
+		wheres = append(wheres, "i.read_at IS NULL")
 	}
 
-	if len(wheres) > 0 {
-		whereSQL = " WHERE " + strings.Join(wheres, " AND ")
+	if filter.StarStatus == ItemStarred {
+		wheres = append(wheres, "i.starred_at IS NOT NULL")
+	} else if filter.StarStatus == ItemUnstarred {
+		wheres = append(wheres, "i.starred_at IS NULL")
 	}
 
+	if !filter.PublishedBefore.IsZero() {
+		// Dates are compared with julianday() as they are stored with the
+		// time zones of the feeds
+		wheres = append(wheres, "julianday(i.published_at) < julianday(?)")
+		params = append(params, filter.PublishedBefore.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	return wheres, params
+}
+
+// limitSQL returns the LIMIT and OFFSET clauses of a filter
+func limitSQL(filter ItemFilter) string {
+	var clause string
+
 	if filter.Limit > 0 {
-		limitSQL = fmt.Sprintf(" LIMIT %d", filter.Limit)
+		clause = fmt.Sprintf(" LIMIT %d", filter.Limit)
 
 		if filter.Offset > 0 {
-			limitSQL = fmt.Sprintf("%s %d", limitSQL, filter.Offset)
+			clause = fmt.Sprintf("%s OFFSET %d", clause, filter.Offset)
 		}
 	}
 
-	rows, err := db.Query(
-		fmt.Sprintf(`SELECT * FROM "%s"%s ORDER BY published_at%s`, itemsTable, whereSQL, limitSQL),
-		params...,
-	)
-	if err != nil {
-		return items, err
-	}
+	return clause
+}
+
+// scanItems reads items selected with itemColumns and closes the rows
+func scanItems(rows *sql.Rows) ([]*Item, error) {
+	var items []*Item
+
 	defer rows.Close()
 	for rows.Next() {
 		i := &Item{}
-		err = rows.Scan(&i.ID, &i.FeedID, &i.GUID, &i.URL, &i.Title, &i.Desc, &i.PublishedAt, &i.ReadAt)
+		err := rows.Scan(&i.ID, &i.FeedID, &i.FeedTitle, &i.GUID, &i.URL, &i.Title, &i.Desc, &i.Content, &i.Author, &i.PublishedAt, &i.UpdatedAt, &i.ReadAt, &i.StarredAt)
 		if err != nil {
 			return items, err
 		}
@@ -139,7 +348,27 @@ func ListItems(db cruderQueryer, filter ItemFilter) ([]*Item, error) {
 		items = append(items, i)
 	}
 
-	return items, nil
+	return items, rows.Err()
+}
+
+// Body returns the article extracted from the page of the item if there is
+// one, or else its description
+func (i Item) Body() string {
+	if i.Content != "" {
+		return i.Content
+	}
+
+	return i.Desc
+}
+
+// SetItemContent stores the article extracted from the page of an item
+func SetItemContent(db cruderExecer, id int64, content string) error {
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET content = ? WHERE id = ?`, itemsTable),
+		content, id,
+	)
+
+	return err
 }
 
 // SetItemsAsReadNow updates the read_at column for all items to CURRENT_TIMESTAMP
@@ -164,9 +393,116 @@ func SetItemsAsReadNow(db cruderExecer, ids ...int64) error {
 	return err
 }
 
-// DeleteItems removes one or more items from DB
+// SetItemsAsUnread clears the read_at column for all items
+func SetItemsAsUnread(db cruderExecer, ids ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) == 0 {
+		return errors.New("missing ids to update")
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET read_at = NULL WHERE id IN (%s)`, itemsTable, strings.Join(placeholders, ",")),
+		params...,
+	)
+
+	return err
+}
+
+// SetItemsAsReadNowByFilter updates the read_at column of the unread items
+// matching the filter to CURRENT_TIMESTAMP without listing them first, the
+// limit of the filter is ignored. Returns the number of items updated.
+func SetItemsAsReadNowByFilter(db cruderExecer, filter ItemFilter) (int64, error) {
+	return setItemsReadByFilter(db, filter, "CURRENT_TIMESTAMP", "i.read_at IS NULL")
+}
+
+// SetItemsAsUnreadByFilter clears the read_at column of the read items
+// matching the filter without listing them first, the limit of the filter is
+// ignored. Returns the number of items updated.
+func SetItemsAsUnreadByFilter(db cruderExecer, filter ItemFilter) (int64, error) {
+	return setItemsReadByFilter(db, filter, "NULL", "i.read_at IS NOT NULL")
+}
+
+// setItemsReadByFilter sets the read_at column of the items matching the
+// filter and the condition
+func setItemsReadByFilter(db cruderExecer, filter ItemFilter, readAt, where string) (int64, error) {
+	wheres, params := itemWheres(filter)
+
+	r, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" AS i SET read_at = %s WHERE %s`, itemsTable, readAt, strings.Join(append(wheres, where), " AND ")),
+		params...,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.RowsAffected()
+}
+
+// SetItemsAsStarredNow updates the starred_at column for all items to
+// CURRENT_TIMESTAMP, items that are already starred keep their starred_at
+func SetItemsAsStarredNow(db cruderExecer, ids ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) == 0 {
+		return errors.New("missing ids to update")
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET starred_at = CURRENT_TIMESTAMP WHERE id IN (%s) AND starred_at IS NULL`, itemsTable, strings.Join(placeholders, ",")),
+		params...,
+	)
+
+	return err
+}
+
+// SetItemsAsUnstarred clears the starred_at column for all items
+func SetItemsAsUnstarred(db cruderExecer, ids ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	for _, id := range ids {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) == 0 {
+		return errors.New("missing ids to update")
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET starred_at = NULL WHERE id IN (%s)`, itemsTable, strings.Join(placeholders, ",")),
+		params...,
+	)
+
+	return err
+}
+
+// DeleteItems removes one or more items from DB, starred items are kept
 func DeleteItems(db cruderExecer, ids ...int64) error {
+	return deleteItems(db, false, ids...)
+}
+
+// ForceDeleteItems removes one or more items from DB, starred items included
+func ForceDeleteItems(db cruderExecer, ids ...int64) error {
+	return deleteItems(db, true, ids...)
+}
+
+func deleteItems(db cruderExecer, force bool, ids ...int64) error {
 	var placeholders []string
+	var starredSQL string
 	var params []interface{}
 
 	for _, id := range ids {
@@ -178,8 +514,12 @@ func DeleteItems(db cruderExecer, ids ...int64) error {
 		return errors.New("missing ids to update")
 	}
 
+	if !force {
+		starredSQL = " AND starred_at IS NULL"
+	}
+
 	_, err := db.Exec(
-		fmt.Sprintf(`DELETE FROM "%s" WHERE id IN (%s)`, itemsTable, strings.Join(placeholders, ",")),
+		fmt.Sprintf(`DELETE FROM "%s" WHERE id IN (%s)%s`, itemsTable, strings.Join(placeholders, ","), starredSQL),
 		params...,
 	)
 
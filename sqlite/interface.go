@@ -12,4 +12,20 @@ type (
 	cruderQueryRower interface {
 		QueryRow(string, ...interface{}) *sql.Row
 	}
+	cruderQueryQueryRower interface {
+		cruderQueryer
+		cruderQueryRower
+	}
+	cruderExecQueryer interface {
+		cruderExecer
+		cruderQueryer
+	}
+	cruderExecQueryRower interface {
+		cruderExecer
+		cruderQueryRower
+	}
+	cruderBeginner interface {
+		cruderExecQueryRower
+		Begin() (*sql.Tx, error)
+	}
 )
@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// prunedItemsTable has the GUIDs of pruned items so they aren't added
+	// again when their feeds are synced
+	prunedItemsTable = "pruned_items"
+)
+
+type (
+	// RetentionPolicy decides which items are pruned, an item is pruned when
+	// it matches all the conditions that are set. Starred items are never
+	// pruned.
+	RetentionPolicy struct {
+		// MaxAge prunes items published longer ago, 0 disables it
+		MaxAge time.Duration
+		// Keep is the number of newest items per feed that are never
+		// pruned, 0 disables it
+		Keep int64
+		// ReadOnly prunes only read items, it has to be combined with MaxAge
+		// or Keep as it would otherwise prune every read item
+		ReadOnly bool
+	}
+
+	// PruneResult is the number of items pruned from a feed
+	PruneResult struct {
+		FeedID int64
+		Pruned int64
+	}
+)
+
+// IsZero returns true if the policy has no conditions, such a policy prunes
+// nothing
+func (p RetentionPolicy) IsZero() bool {
+	return p.MaxAge == 0 && p.Keep == 0 && !p.ReadOnly
+}
+
+// SetFeedRetention overrides the retention policy of a feed, nil values use
+// the policy given to PruneItems
+func SetFeedRetention(db cruderExecer, id int64, maxAge *time.Duration, keep *int64) error {
+	var maxAgeSeconds, keepItems sql.NullInt64
+
+	if maxAge != nil {
+		if *maxAge < 0 {
+			return errors.New("max age of retention can't be negative")
+		}
+		maxAgeSeconds = sql.NullInt64{Int64: int64(*maxAge / time.Second), Valid: true}
+	}
+
+	if keep != nil {
+		if *keep < 0 {
+			return errors.New("number of items to keep can't be negative")
+		}
+		keepItems = sql.NullInt64{Int64: *keep, Valid: true}
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(`UPDATE "%s" SET retention_max_age = ?, retention_keep = ? WHERE id = ?`, feedsTable),
+		maxAgeSeconds, keepItems, id,
+	)
+
+	return err
+}
+
+// PruneItems deletes the items of the feeds matching the filter according to
+// the policy, overridden by the retention of each feed. Items are deleted in
+// a transaction, either all feeds are pruned or none. Returns an error if the
+// policy of a feed prunes only read items without MaxAge or Keep. Returns the
+// number of pruned items per feed.
+func PruneItems(db cruderBeginner, policy RetentionPolicy, filter FeedFilter) ([]PruneResult, error) {
+	var results []PruneResult
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	feeds, err := ListFeeds(tx, filter)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, feed := range feeds {
+		n, err := pruneFeedItems(tx, feed.ID, feed.Retention(policy))
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("could not prune items of feed %d: %s", feed.ID, err)
+		}
+
+		results = append(results, PruneResult{FeedID: feed.ID, Pruned: n})
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// pruneFeedItems deletes the items of a feed matching a policy, returns the
+// number of deleted items
+func pruneFeedItems(db cruderExecer, feedID int64, policy RetentionPolicy) (int64, error) {
+	if policy.IsZero() {
+		return 0, nil
+	}
+
+	if policy.ReadOnly && policy.MaxAge == 0 && policy.Keep == 0 {
+		return 0, errors.New("pruning only read items without a max age or a number of items to keep would delete all of them")
+	}
+
+	wheres := []string{"feed_id = ?", "starred_at IS NULL"}
+	params := []interface{}{feedID}
+
+	if policy.MaxAge > 0 {
+		// Dates are compared with julianday() as they are stored with the
+		// time zones of the feeds
+		wheres = append(wheres, "julianday(published_at) < julianday(?)")
+		params = append(params, time.Now().Add(-policy.MaxAge).UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	if policy.ReadOnly {
+		wheres = append(wheres, "read_at IS NOT NULL")
+	}
+
+	if policy.Keep > 0 {
+		wheres = append(wheres, fmt.Sprintf(
+			`id NOT IN (SELECT id FROM "%s" WHERE feed_id = ? ORDER BY julianday(published_at) DESC, id DESC LIMIT %d)`,
+			itemsTable, policy.Keep,
+		))
+		params = append(params, feedID)
+	}
+
+	whereSQL := strings.Join(wheres, " AND ")
+
+	_, err := db.Exec(
+		fmt.Sprintf(`INSERT OR IGNORE INTO "%s" (feed_id, guid) SELECT feed_id, guid FROM "%s" WHERE %s`, prunedItemsTable, itemsTable, whereSQL),
+		params...,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := db.Exec(
+		fmt.Sprintf(`DELETE FROM "%s" WHERE %s`, itemsTable, whereSQL),
+		params...,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.RowsAffected()
+}
+
+// ExpirePrunedItems forgets the pruned items of a feed that are no longer in
+// the items of the feed, as they won't be added again. Returns the number of
+// forgotten items.
+func ExpirePrunedItems(db cruderExecQueryer, feedID int64, items ...Item) (int64, error) {
+	var expired []string
+
+	current := make(map[string]bool)
+	for _, item := range items {
+		current[item.GUID] = true
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`SELECT guid FROM "%s" WHERE feed_id = ?`, prunedItemsTable), feedID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guid string
+
+		err = rows.Scan(&guid)
+		if err != nil {
+			return 0, err
+		}
+
+		if !current[guid] {
+			expired = append(expired, guid)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return 0, err
+	}
+
+	var forgotten int64
+
+	// The ID of the feed is a parameter too
+	err = Batches(len(expired), maxParams-1, func(start, end int) error {
+		placeholders := make([]string, 0, end-start)
+		params := []interface{}{feedID}
+
+		for _, guid := range expired[start:end] {
+			placeholders = append(placeholders, "?")
+			params = append(params, guid)
+		}
+
+		r, err := db.Exec(
+			fmt.Sprintf(`DELETE FROM "%s" WHERE feed_id = ? AND guid IN (%s)`, prunedItemsTable, strings.Join(placeholders, ",")),
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+
+		n, err := r.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		forgotten += n
+
+		return nil
+	})
+
+	return forgotten, err
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	itemsFTSTable = "items_fts"
+)
+
+// ErrSearchUnavailable is returned when searching with a SQLite library that
+// hasn't been compiled with FTS5
+var ErrSearchUnavailable = errors.New("full-text search is unavailable, build feeda with the sqlite_fts5 tag to enable it")
+
+// searchAvailable returns true if the SQLite library supports FTS5
+func searchAvailable(db cruderQueryRower) (bool, error) {
+	var available bool
+
+	err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&available)
+
+	return available, err
+}
+
+// ensureSearchIndex creates the full-text index of items and the triggers
+// keeping it in sync. The index isn't part of the migrations as FTS5 is
+// optional, when it's unavailable the triggers are dropped to still allow
+// writing items. The index is rebuilt whenever the triggers are created as
+// items might have changed without them. The update trigger only fires when
+// indexed columns change so reading, starring and fetching articles don't
+// rewrite the index.
+func ensureSearchIndex(db cruderExecQueryRower) error {
+	available, err := searchAvailable(db)
+	if err != nil {
+		return err
+	}
+
+	if !available {
+		_, err = db.Exec(fmt.Sprintf(
+			`DROP TRIGGER IF EXISTS "%[1]s_ai";
+			DROP TRIGGER IF EXISTS "%[1]s_ad";
+			DROP TRIGGER IF EXISTS "%[1]s_au";`,
+			itemsFTSTable,
+		))
+
+		return err
+	}
+
+	// Update triggers of older versions fired on updates of any column and
+	// are replaced
+	var triggers int64
+	err = db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?) AND instr(sql, 'AFTER UPDATE ON') = 0`,
+		itemsFTSTable+"_ai", itemsFTSTable+"_ad", itemsFTSTable+"_au",
+	).Scan(&triggers)
+	if err != nil || triggers == 3 {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS "%[1]s" USING fts5(title, desc, author, content='%[2]s', content_rowid='id');
+		CREATE TRIGGER IF NOT EXISTS "%[1]s_ai" AFTER INSERT ON "%[2]s" BEGIN
+			INSERT INTO "%[1]s" (rowid, title, desc, author) VALUES (new.id, new.title, new.desc, new.author);
+		END;
+		CREATE TRIGGER IF NOT EXISTS "%[1]s_ad" AFTER DELETE ON "%[2]s" BEGIN
+			INSERT INTO "%[1]s" ("%[1]s", rowid, title, desc, author) VALUES ('delete', old.id, old.title, old.desc, old.author);
+		END;
+		DROP TRIGGER IF EXISTS "%[1]s_au";
+		CREATE TRIGGER "%[1]s_au" AFTER UPDATE OF title, desc, author ON "%[2]s" BEGIN
+			INSERT INTO "%[1]s" ("%[1]s", rowid, title, desc, author) VALUES ('delete', old.id, old.title, old.desc, old.author);
+			INSERT INTO "%[1]s" (rowid, title, desc, author) VALUES (new.id, new.title, new.desc, new.author);
+		END;
+		INSERT INTO "%[1]s" ("%[1]s") VALUES ('rebuild');`,
+		itemsFTSTable, itemsTable,
+	))
+
+	return err
+}
+
+// SearchItems returns the items matching a full-text query in FTS5 syntax,
+// ordered by relevance. Items are matched by their title, description and
+// author.
+func SearchItems(db cruderQueryQueryRower, query string, filter ItemFilter) ([]*Item, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("missing query to search for")
+	}
+
+	available, err := searchAvailable(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !available {
+		return nil, ErrSearchUnavailable
+	}
+
+	wheres, params := itemWheres(filter)
+	wheres = append([]string{fmt.Sprintf(`"%s" MATCH ?`, itemsFTSTable)}, wheres...)
+	params = append([]interface{}{query}, params...)
+
+	rows, err := db.Query(
+		fmt.Sprintf(
+			`SELECT %[1]s FROM "%[2]s" JOIN "%[3]s" i ON i.id = "%[2]s".rowid JOIN "%[4]s" f ON f.id = i.feed_id
+			WHERE %[5]s ORDER BY "%[2]s".rank%[6]s`,
+			itemColumns, itemsFTSTable, itemsTable, feedsTable, strings.Join(wheres, " AND "), limitSQL(filter),
+		),
+		params...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanItems(rows)
+}
@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	tagsTable     = "tags"
+	feedTagsTable = "feed_tags"
+)
+
+// feedIDsByTagSQL selects the IDs of the feeds tagged with the tag given as
+// parameter
+var feedIDsByTagSQL = fmt.Sprintf(
+	`SELECT ft.feed_id FROM "%s" ft JOIN "%s" t ON t.id = ft.tag_id WHERE t.name = ?`,
+	feedTagsTable, tagsTable,
+)
+
+type (
+	// Tag groups feeds, a feed can have many tags
+	Tag struct {
+		ID     int64  `json:"id"`
+		Name   string `json:"name"`
+		Feeds  int64  `json:"feeds"`
+		Unread int64  `json:"unread"`
+	}
+)
+
+// TagFeeds tags feeds with a tag, the tag is created if it doesn't exist.
+// Tags are case-insensitive.
+func TagFeeds(db cruderExecer, tag string, feedIDs ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	tag, err := validTag(tag)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range feedIDs {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) == 0 {
+		return errors.New("missing ids to tag")
+	}
+
+	_, err = db.Exec(fmt.Sprintf(`INSERT OR IGNORE INTO "%s" (name) VALUES (?)`, tagsTable), tag)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		fmt.Sprintf(
+			`INSERT OR IGNORE INTO "%s" (feed_id, tag_id)
+			SELECT f.id, t.id FROM "%s" f, "%s" t WHERE f.id IN (%s) AND t.name = ?`,
+			feedTagsTable, feedsTable, tagsTable, strings.Join(placeholders, ","),
+		),
+		append(params, tag)...,
+	)
+
+	return err
+}
+
+// UntagFeeds removes a tag from feeds, the tag is deleted when no feeds are
+// tagged with it anymore
+func UntagFeeds(db cruderExecer, tag string, feedIDs ...int64) error {
+	var placeholders []string
+	var params []interface{}
+
+	for _, id := range feedIDs {
+		placeholders = append(placeholders, "?")
+		params = append(params, id)
+	}
+
+	if len(placeholders) == 0 {
+		return errors.New("missing ids to untag")
+	}
+
+	_, err := db.Exec(
+		fmt.Sprintf(
+			`DELETE FROM "%s" WHERE feed_id IN (%s) AND tag_id IN (SELECT id FROM "%s" WHERE name = ?)`,
+			feedTagsTable, strings.Join(placeholders, ","), tagsTable,
+		),
+		append(params, strings.TrimSpace(tag))...,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		fmt.Sprintf(`DELETE FROM "%s" WHERE id NOT IN (SELECT tag_id FROM "%s")`, tagsTable, feedTagsTable),
+	)
+
+	return err
+}
+
+// ListTags returns the tags that feeds are tagged with together with the
+// number of feeds and unread items for each tag
+func ListTags(db cruderQueryer) ([]*Tag, error) {
+	var tags []*Tag
+
+	rows, err := db.Query(
+		fmt.Sprintf(
+			`SELECT t.id, t.name, COUNT(ft.feed_id),
+				(SELECT COUNT(i.id) FROM "%[1]s" i JOIN "%[2]s" ift ON ift.feed_id = i.feed_id WHERE ift.tag_id = t.id AND i.read_at IS NULL)
+			FROM "%[3]s" t JOIN "%[2]s" ft ON ft.tag_id = t.id GROUP BY t.id ORDER BY t.name`,
+			itemsTable, feedTagsTable, tagsTable,
+		),
+	)
+	if err != nil {
+		return tags, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		t := &Tag{}
+		err = rows.Scan(&t.ID, &t.Name, &t.Feeds, &t.Unread)
+		if err != nil {
+			return tags, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// validTag returns the tag without surrounding spaces, tags can't contain
+// commas as ListFeeds joins them with commas
+func validTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", errors.New("missing tag")
+	}
+
+	if strings.Contains(tag, ",") {
+		return "", fmt.Errorf("tag %q can't contain commas", tag)
+	}
+
+	return tag, nil
+}